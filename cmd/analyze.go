@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"fdups/finder"
+	"fdups/hasher"
+	"fdups/log"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// chunkingMethod holds the --chunking flag value.
+	chunkingMethod string
+	// topFiles holds the --top flag value.
+	topFiles int
+)
+
+// analyzeCmd represents the analyze command.
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze <directory>",
+	Short: "Estimate the savings of block-level deduplication",
+	Long: "Scan a directory recursively, split every file into content-defined chunks and " +
+		"report the total bytes against the bytes left after storing each distinct file and " +
+		"each distinct chunk once, along with the files sharing the most chunks with others.",
+	Args: cobra.ExactArgs(1),
+	Run:  runAnalyze,
+}
+
+func init() {
+	analyzeCmd.Flags().StringVar(&chunkingMethod, "chunking", string(hasher.FastCDC),
+		"Chunking method: "+strings.Join(chunkingMethodNames(), ", "))
+	analyzeCmd.Flags().IntVar(&topFiles, "top", 10, "Number of files sharing the most chunks to list")
+	analyzeCmd.Flags().Int64Var(&minSize, "min-size", 0, "Skip files smaller than this many bytes")
+	analyzeCmd.Flags().Int64Var(&maxSize, "max-size", 0, "Skip files larger than this many bytes (0 for no limit)")
+	analyzeCmd.Flags().StringVar(&hashAlgorithm, "hash", string(hasher.SHA256),
+		"Hash algorithm for files and chunks: "+strings.Join(algorithmNames(), ", "))
+	analyzeCmd.Flags().BoolVar(&scanArchives, "archives", false,
+		"Also process the files inside zip, tar and tar.gz archives, reported as archive.zip!/member")
+	addWalkFlags(analyzeCmd)
+	addReportSkippedFlag(analyzeCmd)
+
+	_ = analyzeCmd.RegisterFlagCompletionFunc("chunking",
+		cobra.FixedCompletions(chunkingMethodNames(), cobra.ShellCompDirectiveNoFileComp))
+	_ = analyzeCmd.RegisterFlagCompletionFunc("hash",
+		cobra.FixedCompletions(algorithmNames(), cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.AddCommand(analyzeCmd)
+}
+
+// runAnalyze is the main entry point for the analyze command.
+func runAnalyze(_ *cobra.Command, args []string) {
+	method, err := hasher.ParseChunkingMethod(chunkingMethod)
+	if err != nil {
+		log.L().Fatal("Unknown chunking method",
+			zap.String("chunking", chunkingMethod),
+			zap.Strings("valid", chunkingMethodNames()))
+	}
+
+	directory := resolveDirectory(args[0])
+	analyzer := finder.NewChunkAnalyzer(directory, method, finderOptions()...)
+
+	log.L().Info("Program started", zap.String("target", directory))
+	start := time.Now()
+
+	err, report := analyzer.Analyze(topFiles)
+	if err != nil {
+		log.L().Fatal("Program terminated with error", zap.Error(err))
+	}
+
+	log.L().Info("Program completed successfully", zap.Duration("duration", time.Since(start)))
+	outputResult(withSkipped(analyzer, report))
+}
+
+// chunkingMethodNames returns the names of all supported chunking methods.
+func chunkingMethodNames() []string {
+	var names []string
+	for _, method := range hasher.ChunkingMethods() {
+		names = append(names, string(method))
+	}
+	return names
+}
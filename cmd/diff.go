@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fdups/finder"
+
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command.
+var diffCmd = &cobra.Command{
+	Use:   "diff <source> <destination>",
+	Short: "Compare the contents of two directory trees",
+	Long: "Hash two directory trees and report content found only in the source, " +
+		"content found only in the destination, and content stored under a different path. " +
+		"The similar-images and similar-text finders are not supported.",
+	Args: cobra.ExactArgs(2),
+	Run:  runDiff,
+}
+
+func init() {
+	addFinderFlags(diffCmd)
+	rootCmd.AddCommand(diffCmd)
+}
+
+// runDiff is the main entry point for the diff command.
+func runDiff(cmd *cobra.Command, args []string) {
+	source := resolveDirectory(args[0])
+	destination := resolveDirectory(args[1])
+
+	sourceResult := executeFinder(createExactFinder(finderType, source, finderOptions()...), source)
+	destinationResult := executeFinder(createExactFinder(finderType, destination, finderOptions()...), destination)
+
+	outputResult(finder.Diff(source, sourceResult, destination, destinationResult))
+}
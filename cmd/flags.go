@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"fdups/finder"
+	"fdups/hasher"
+	"fdups/log"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	// finderType holds the --finder flag value.
+	finderType string
+	// minSize holds the --min-size flag value.
+	minSize int64
+	// maxSize holds the --max-size flag value.
+	maxSize int64
+	// hashAlgorithm holds the --hash flag value.
+	hashAlgorithm string
+	// routeSpecs holds the --route flag values.
+	routeSpecs []string
+	// flacStreamInfoMD5 holds the --flac-md5 flag value.
+	flacStreamInfoMD5 bool
+	// verifyChecksums holds the --verify flag value.
+	verifyChecksums bool
+	// textNormalizations holds the --normalize flag values.
+	textNormalizations []string
+	// scanArchives holds the --archives flag value.
+	scanArchives bool
+	// ignoreVolatileParts holds the --ignore-volatile flag value.
+	ignoreVolatileParts bool
+	// symlinkPolicy holds the --symlinks flag value.
+	symlinkPolicy string
+	// oneFileSystem holds the --one-file-system flag value.
+	oneFileSystem bool
+	// excludedFsTypes holds the --exclude-fstype flag values.
+	excludedFsTypes []string
+	// reportSkipped holds the --report-skipped flag value.
+	reportSkipped bool
+	// directoryWorkers holds the --walkers flag value.
+	directoryWorkers int
+)
+
+// addFinderFlags registers the flags that select and configure a finder.
+func addFinderFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&finderType, "finder", "default", "Finder type, one of:\n"+finderUsage())
+	cmd.Flags().Int64Var(&minSize, "min-size", 0, "Skip files smaller than this many bytes")
+	cmd.Flags().Int64Var(&maxSize, "max-size", 0, "Skip files larger than this many bytes (0 for no limit)")
+	cmd.Flags().StringVar(&hashAlgorithm, "hash", string(hasher.SHA256),
+		"Hash algorithm: "+strings.Join(algorithmNames(), ", "))
+	cmd.Flags().StringArrayVar(&routeSpecs, "route", nil,
+		"Route files to a hasher with --finder mixed, as .ext=HASHER or type/subtype=HASHER (hashers: "+
+			strings.Join(routeHasherNames(), ", ")+")")
+	cmd.Flags().BoolVar(&flacStreamInfoMD5, "flac-md5", false,
+		"Group FLAC files by the audio MD5 in STREAMINFO instead of decoding them")
+	cmd.Flags().BoolVar(&verifyChecksums, "verify", false,
+		"Decode content anyway and verify embedded checksums such as the FLAC audio MD5")
+	cmd.Flags().StringSliceVar(&textNormalizations, "normalize", hasher.TextNormalizationNames(),
+		"Differences ignored by the text hasher, any of: "+strings.Join(hasher.TextNormalizationNames(), ", ")+
+			" (none for exact content)")
+
+	cmd.Flags().BoolVar(&ignoreVolatileParts, "ignore-volatile", false,
+		"Ignore office document properties that change on every save, such as the modification date")
+	cmd.Flags().BoolVar(&scanArchives, "archives", false,
+		"Also process the files inside zip, tar and tar.gz archives, reported as archive.zip!/member")
+	addWalkFlags(cmd)
+	addReportSkippedFlag(cmd)
+
+	_ = cmd.RegisterFlagCompletionFunc("finder", completeFinders)
+	_ = cmd.RegisterFlagCompletionFunc("hash", cobra.FixedCompletions(algorithmNames(), cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("normalize",
+		cobra.FixedCompletions(append(hasher.TextNormalizationNames(), "none"), cobra.ShellCompDirectiveNoFileComp))
+}
+
+// finderOptions returns the finder options selected by the finder flags.
+func finderOptions() []finder.Option {
+	algorithm, err := hasher.ParseAlgorithm(hashAlgorithm)
+	if err != nil {
+		log.L().Fatal("Unknown hash algorithm",
+			zap.String("hash", hashAlgorithm),
+			zap.Strings("valid", algorithmNames()))
+	}
+
+	hasherOptions := []hasher.Option{hasher.WithAlgorithm(algorithm), hasher.WithTextNormalization(textNormalization())}
+	if flacStreamInfoMD5 {
+		hasherOptions = append(hasherOptions, hasher.WithStreamInfoMD5())
+	}
+	if verifyChecksums {
+		hasherOptions = append(hasherOptions, hasher.WithVerify())
+	}
+	if ignoreVolatileParts {
+		hasherOptions = append(hasherOptions, hasher.WithIgnoreVolatileParts())
+	}
+
+	options := []finder.Option{finder.WithHasherOptions(hasherOptions...)}
+	if minSize > 0 || maxSize > 0 {
+		options = append(options, finder.WithFileFilter(finder.SizeFilter(minSize, maxSize)))
+	}
+	if scanArchives {
+		options = append(options, finder.WithArchives())
+	}
+	options = append(options, finder.WithSymlinks(parseSymlinkPolicy()), finder.WithDirectoryWorkers(directoryWorkers))
+	if oneFileSystem {
+		options = append(options, finder.WithOneFileSystem())
+	}
+	if len(excludedFsTypes) > 0 {
+		options = append(options, finder.WithExcludedFilesystemTypes(excludedFsTypes...))
+	}
+	if len(routeSpecs) > 0 && finderType != "mixed" {
+		log.L().Fatal("Routes are only supported by the mixed finder", zap.String("finder", finderType))
+	}
+	for _, spec := range routeSpecs {
+		options = append(options, finder.WithRoutes(parseRoute(spec)))
+	}
+	return options
+}
+
+// addWalkFlags registers the flags that control the directory walk on cmd.
+func addWalkFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&symlinkPolicy, "symlinks", string(finder.SkipSymlinks),
+		"Symbolic link handling: skip, report (list links without following them) or follow "+
+			"(walk linked directories once each); dangling links are reported unless skipped")
+	cmd.Flags().BoolVar(&oneFileSystem, "one-file-system", false,
+		"Skip directories on other filesystems than the scanned directory, like du -x")
+	cmd.Flags().StringSliceVar(&excludedFsTypes, "exclude-fstype", nil,
+		"Skip directories on filesystems of these types, such as nfs,fuse (Linux only)")
+	cmd.Flags().IntVar(&directoryWorkers, "walkers", finder.DefaultDirectoryWorkers,
+		"Number of directories read concurrently while walking")
+	_ = cmd.RegisterFlagCompletionFunc("symlinks",
+		cobra.FixedCompletions(symlinkPolicyNames(), cobra.ShellCompDirectiveNoFileComp))
+}
+
+// addReportSkippedFlag registers the --report-skipped flag on cmd.
+func addReportSkippedFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&reportSkipped, "report-skipped", false,
+		"Wrap the output with the symlinks, special files such as FIFOs and device nodes, "+
+			"unreadable paths and files their hasher cannot parse left out of the result")
+}
+
+// parseSymlinkPolicy returns the symlink policy selected by --symlinks.
+func parseSymlinkPolicy() finder.SymlinkPolicy {
+	policy, err := finder.ParseSymlinkPolicy(symlinkPolicy)
+	if err != nil {
+		log.L().Fatal("Unknown symlink policy",
+			zap.String("symlinks", symlinkPolicy),
+			zap.Strings("valid", symlinkPolicyNames()))
+	}
+	return policy
+}
+
+// symlinkPolicyNames returns the names of all supported symlink policies.
+func symlinkPolicyNames() []string {
+	var names []string
+	for _, policy := range finder.SymlinkPolicies() {
+		names = append(names, string(policy))
+	}
+	return names
+}
+
+// textNormalization returns the text normalizations selected by --normalize.
+func textNormalization() hasher.TextNormalization {
+	if len(textNormalizations) == 1 && textNormalizations[0] == "none" {
+		return 0
+	}
+	normalization, err := hasher.ParseTextNormalization(textNormalizations)
+	if err != nil {
+		log.L().Fatal("Unknown text normalization",
+			zap.Strings("normalize", textNormalizations),
+			zap.Strings("valid", hasher.TextNormalizationNames()))
+	}
+	return normalization
+}
+
+// parseRoute parses a --route value of the form MATCH=HASHER, where MATCH is
+// either a file extension starting with a dot or a MIME type.
+func parseRoute(spec string) finder.Route {
+	match, hasherName, found := strings.Cut(spec, "=")
+	registration, ok := hasher.Lookup(hasherName)
+	if ok {
+		_, similarity := registration.New().(hasher.SimilarityHasher)
+		ok = !similarity
+	}
+	if !found || !ok {
+		log.L().Fatal("Invalid route",
+			zap.String("route", spec),
+			zap.Strings("hashers", routeHasherNames()))
+	}
+
+	route := finder.Route{Hasher: registration.New}
+	switch {
+	case strings.HasPrefix(match, "."):
+		route.Extensions = []string{match}
+	case strings.Contains(match, "/"):
+		route.MIMETypes = []string{match}
+	default:
+		log.L().Fatal("Invalid route match, expected .ext or type/subtype", zap.String("route", spec))
+	}
+	return route
+}
+
+// routeHasherNames returns the names of the registered hashers files can be
+// routed to. Similarity hashers are left out, as their hashes only make
+// sense when compared by similarity rather than grouped by equality.
+func routeHasherNames() []string {
+	var names []string
+	for _, registration := range hasher.Registrations() {
+		if _, similarity := registration.New().(hasher.SimilarityHasher); !similarity {
+			names = append(names, registration.Name)
+		}
+	}
+	return names
+}
+
+// finderUsage describes the registered finders, one per line.
+func finderUsage() string {
+	registrations := finder.Registrations()
+	width := 0
+	for _, registration := range registrations {
+		width = max(width, len(registration.Name))
+	}
+
+	var usage strings.Builder
+	for _, registration := range registrations {
+		_, _ = fmt.Fprintf(&usage, "  %-*s  %s\n", width, registration.Name, registration.Description)
+	}
+	return usage.String()
+}
+
+// finderNames returns the names of all registered finders.
+func finderNames() []string {
+	var names []string
+	for _, registration := range finder.Registrations() {
+		names = append(names, registration.Name)
+	}
+	return names
+}
+
+// completeFinders completes the --finder flag with the registered finders.
+func completeFinders(*cobra.Command, []string, string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	var completions []cobra.Completion
+	for _, registration := range finder.Registrations() {
+		completions = append(completions, cobra.CompletionWithDesc(registration.Name, registration.Description))
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// algorithmNames returns the names of all supported hash algorithms.
+func algorithmNames() []string {
+	var names []string
+	for _, algorithm := range hasher.Algorithms() {
+		names = append(names, string(algorithm))
+	}
+	return names
+}
@@ -3,6 +3,10 @@
 // The CLI provides subcommands for various duplicate file detection operations.
 // Currently supported commands:
 //   - scan: Scan a directory for duplicate files
+//   - diff: Compare the contents of two directory trees
+//   - unique: List files whose content exists only once
+//   - verify: Check files for corruption
+//   - analyze: Estimate the savings of block-level deduplication
 //
 // Usage:
 //
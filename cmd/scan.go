@@ -14,29 +14,159 @@ import (
 	"go.uber.org/zap"
 )
 
-// finderType holds the --finder flag value.
-var finderType string
+var (
+	// groupDirectories holds the --dirs flag value.
+	groupDirectories bool
+	// directoriesContentOnly holds the --dirs-content-only flag value.
+	directoriesContentOnly bool
+	// filesFrom holds the --files-from flag value.
+	filesFrom string
+	// nullSeparated holds the --null flag value.
+	nullSeparated bool
+	// maxDistance holds the --max-distance flag value.
+	maxDistance int
+	// minSimilarity holds the --threshold flag value.
+	minSimilarity float64
+	// prefixMatches holds the --prefix-matches flag value.
+	prefixMatches bool
+)
+
+// unfilteredFinders holds the finders processing every file, whose results
+// describe complete directories.
+var unfilteredFinders = map[string]bool{"default": true, "mixed": true, "text": true}
 
 // scanCmd represents the scan command.
 var scanCmd = &cobra.Command{
-	Use:   "scan <directory>",
+	Use:   "scan [directory]",
 	Short: "Scan a directory for duplicate files",
-	Long:  "Scan a directory recursively and find duplicate files based on content hash.",
-	Args:  cobra.ExactArgs(1),
-	Run:   runScan,
+	Long: "Scan a directory recursively and find duplicate files based on content hash.\n\n" +
+		"With --files-from, the listed files are scanned instead, and relative entries are " +
+		"resolved against the directory, which defaults to the current working directory.\n\n" +
+		"Finders detecting near-duplicates, such as similar-images and similar-text, report groups of similar " +
+		"files with a similarity score per file instead of groups of identical files.\n\n" +
+		"With --prefix-matches, files whose content is the beginning of a larger file, such as " +
+		"interrupted copies, are reported as partial copies alongside the duplicate groups.",
+	Args: scanArgs,
+	Run:  runScan,
 }
 
 func init() {
-	scanCmd.Flags().StringVar(&finderType, "finder", "default", "Finder type: default, flac")
+	addFinderFlags(scanCmd)
+	scanCmd.Flags().BoolVar(&groupDirectories, "dirs", false,
+		"Report duplicate directory trees as single entries; only the files the finder processes are "+
+			"compared, so filtered files and empty subdirectories do not tell directories apart")
+	scanCmd.Flags().BoolVar(&directoriesContentOnly, "dirs-content-only", false, "Match directories by content, ignoring names (implies --dirs)")
+	scanCmd.Flags().StringVar(&filesFrom, "files-from", "", "Read the files to scan from this file instead of walking (- for stdin)")
+	scanCmd.Flags().BoolVar(&nullSeparated, "null", false, "Entries read by --files-from are NUL-separated instead of newline-separated")
+	scanCmd.Flags().IntVar(&maxDistance, "max-distance", finder.DefaultMaxDistance,
+		"Maximum perceptual hash distance (0-64) of images grouped by the similar-images finder")
+	scanCmd.Flags().Float64Var(&minSimilarity, "threshold", finder.DefaultMinSimilarity,
+		"Minimum estimated Jaccard similarity (0-1) of documents grouped by the similar-text finder")
+	scanCmd.Flags().BoolVar(&prefixMatches, "prefix-matches", false,
+		"Also report files of at least 4 KiB that are partial copies of larger files")
+	scanCmd.MarkFlagsMutuallyExclusive("dirs", "prefix-matches")
+	scanCmd.MarkFlagsMutuallyExclusive("dirs-content-only", "prefix-matches")
 	rootCmd.AddCommand(scanCmd)
 }
 
+// scanArgs validates the positional arguments of the scan command.
+// The directory may only be omitted when reading files from a list.
+func scanArgs(cmd *cobra.Command, args []string) error {
+	if filesFrom != "" {
+		return cobra.MaximumNArgs(1)(cmd, args)
+	}
+	return cobra.ExactArgs(1)(cmd, args)
+}
+
 // runScan is the main entry point for the scan command.
 func runScan(cmd *cobra.Command, args []string) {
-	directory := resolveDirectory(args[0])
-	f := createFinder(finderType, directory)
+	target := "."
+	if len(args) > 0 {
+		target = args[0]
+	}
+	directory := resolveDirectory(target)
+
+	if maxDistance < 0 || maxDistance > 64 {
+		log.L().Fatal("Maximum distance out of range", zap.Int("max-distance", maxDistance))
+	}
+	if minSimilarity < 0 || minSimilarity > 1 {
+		log.L().Fatal("Similarity threshold out of range", zap.Float64("threshold", minSimilarity))
+	}
+
+	options := append(finderOptions(),
+		finder.WithMaxDistance(maxDistance),
+		finder.WithMinSimilarity(minSimilarity))
+	if filesFrom != "" {
+		fileList := openFileList(filesFrom)
+		defer func() { _ = fileList.Close() }()
+		options = append(options, finder.WithFileList(fileList, listSeparator(nullSeparated)))
+	}
+
+	f := createFinder(finderType, directory, options...)
+	if similarityFinder, ok := f.(finder.SimilarityFinder); ok {
+		if groupDirectories || directoriesContentOnly || prefixMatches {
+			log.L().Fatal("Directory grouping and prefix matching are not supported by similarity finders",
+				zap.String("finder", finderType))
+		}
+		outputResult(withSkipped(f, executeSimilarityFinder(similarityFinder, directory)))
+		return
+	}
+
+	if (groupDirectories || directoriesContentOnly) && (minSize > 0 || maxSize > 0 || !unfilteredFinders[finderType]) {
+		log.L().Warn("Directories are compared by the filtered files only",
+			zap.String("finder", finderType), zap.Int64("min-size", minSize), zap.Int64("max-size", maxSize))
+	}
+
 	result := executeFinder(f, directory)
-	outputResult(result)
+	if groupDirectories || directoriesContentOnly {
+		outputResult(withSkipped(f, finder.GroupDirectories(result, directory, directoriesContentOnly)))
+		return
+	}
+	if prefixMatches {
+		outputResult(withSkipped(f, finder.PrefixResult{Files: result, PartialCopies: finder.FindPrefixMatches(result)}))
+		return
+	}
+	outputResult(withSkipped(f, result))
+}
+
+// skippedReport is the output of a command run with --report-skipped,
+// --symlinks=report, --symlinks=follow or --verify, listing the entries left
+// out of the result next to it.
+type skippedReport struct {
+	// Result is the output of the command.
+	Result interface{} `json:"result"`
+	// Symlinks lists the links that were reported instead of followed.
+	Symlinks []finder.Symlink `json:"symlinks"`
+	// Dangling lists the links whose target could not be resolved.
+	Dangling []finder.Symlink `json:"dangling"`
+	// Special lists the files that are not regular files.
+	Special []finder.SpecialFile `json:"special"`
+	// Unreadable lists the paths that could not be read for lack of permission.
+	Unreadable []finder.UnreadableFile `json:"unreadable"`
+	// Unparseable lists the files that are not valid in the format their
+	// hasher expects.
+	Unparseable []finder.UnparseableFile `json:"unparseable"`
+	// Corrupt lists the files that failed the integrity check of --verify.
+	Corrupt []finder.CorruptFile `json:"corrupt"`
+}
+
+// withSkipped returns result along with the entries the finder or analyzer
+// f left out of it, or result alone unless they are to be reported.
+func withSkipped(f interface{}, result interface{}) interface{} {
+	reporter, ok := f.(finder.DiagnosticsReporter)
+	if !ok || (!reportSkipped && !verifyChecksums && parseSymlinkPolicy() == finder.SkipSymlinks) {
+		return result
+	}
+	diagnostics := reporter.Diagnostics()
+	return skippedReport{
+		Result:      result,
+		Symlinks:    diagnostics.Symlinks,
+		Dangling:    diagnostics.Dangling,
+		Special:     diagnostics.Special,
+		Unreadable:  diagnostics.Unreadable,
+		Unparseable: diagnostics.Unparseable,
+		Corrupt:     diagnostics.Corrupt,
+	}
 }
 
 // resolveDirectory converts a relative path to an absolute path.
@@ -52,19 +182,47 @@ func resolveDirectory(directory string) string {
 	return path.Join(cwd, path.Clean(directory))
 }
 
-// createFinder returns a Finder based on the specified type.
-func createFinder(finderType, directory string) finder.Finder {
-	switch finderType {
-	case "default":
-		return finder.NewDefaultFinder(directory)
-	case "flac":
-		return finder.NewFlacFinder(directory)
-	default:
+// openFileList opens the file list named by --files-from, where "-" denotes stdin.
+func openFileList(name string) *os.File {
+	if name == "-" {
+		return os.Stdin
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		log.L().Fatal("Failed to open file list", zap.String("path", name), zap.Error(err))
+	}
+	return file
+}
+
+// listSeparator returns the entry separator for --files-from input.
+func listSeparator(null bool) byte {
+	if null {
+		return '\x00'
+	}
+	return '\n'
+}
+
+// createFinder returns the registered Finder of the specified type.
+func createFinder(finderType, directory string, options ...finder.Option) finder.Finder {
+	f, err := finder.New(finderType, directory, options...)
+	if err != nil {
 		log.L().Fatal("Unknown finder type",
 			zap.String("type", finderType),
-			zap.Strings("valid", []string{"default", "flac"}))
-		return nil
+			zap.Strings("valid", finderNames()))
 	}
+	return f
+}
+
+// createExactFinder returns the registered Finder of the specified type for
+// commands that compare exact duplicate groups, which similarity finders do
+// not produce.
+func createExactFinder(finderType, directory string, options ...finder.Option) finder.Finder {
+	f := createFinder(finderType, directory, options...)
+	if _, ok := f.(finder.SimilarityFinder); ok {
+		log.L().Fatal("Similarity finders are not supported by this command",
+			zap.String("finder", finderType))
+	}
+	return f
 }
 
 // executeFinder runs the finder and returns the results.
@@ -81,8 +239,23 @@ func executeFinder(f finder.Finder, directory string) map[string][]finder.FileIn
 	return result
 }
 
+// executeSimilarityFinder runs the similarity finder and returns the groups
+// of similar files.
+func executeSimilarityFinder(f finder.SimilarityFinder, directory string) []finder.SimilarityGroup {
+	log.L().Info("Program started", zap.String("target", directory))
+	start := time.Now()
+
+	err, groups := f.FindSimilar()
+	if err != nil {
+		log.L().Fatal("Program terminated with error", zap.Error(err))
+	}
+
+	log.L().Info("Program completed successfully", zap.Duration("duration", time.Since(start)))
+	return groups
+}
+
 // outputResult marshals the result to JSON and prints it to stdout.
-func outputResult(result map[string][]finder.FileInfo) {
+func outputResult(result interface{}) {
 	jsonResult, err := json.Marshal(result)
 	if err != nil {
 		log.L().Fatal("Failed to marshal result", zap.Error(err))
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"sort"
+
+	"fdups/finder"
+	"fdups/log"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// uniqueSortOrder holds the --sort flag value.
+var uniqueSortOrder string
+
+// uniqueCmd represents the unique command.
+var uniqueCmd = &cobra.Command{
+	Use:   "unique <directory>",
+	Short: "List files whose content exists only once",
+	Long: "Scan a directory recursively and list the files without a content duplicate. " +
+		"Hardlinks to the same file do not count as duplicates. The similar-images and " +
+		"similar-text finders are not supported.",
+	Args: cobra.ExactArgs(1),
+	Run:  runUnique,
+}
+
+func init() {
+	addFinderFlags(uniqueCmd)
+	uniqueCmd.Flags().StringVar(&uniqueSortOrder, "sort", "path", "Sort order: path, size")
+	rootCmd.AddCommand(uniqueCmd)
+}
+
+// runUnique is the main entry point for the unique command.
+func runUnique(cmd *cobra.Command, args []string) {
+	directory := resolveDirectory(args[0])
+	f := createExactFinder(finderType, directory, finderOptions()...)
+	unique := finder.UniqueFiles(executeFinder(f, directory))
+	sortUniqueFiles(unique, uniqueSortOrder)
+	outputResult(withSkipped(f, unique))
+}
+
+// sortUniqueFiles orders files, which are sorted by path, as requested by order.
+// Sorting by size puts the largest files first.
+func sortUniqueFiles(files []finder.FileInfo, order string) {
+	switch order {
+	case "path":
+	case "size":
+		sort.SliceStable(files, func(i, j int) bool { return files[i].Size > files[j].Size })
+	default:
+		log.L().Fatal("Unknown sort order",
+			zap.String("sort", order),
+			zap.Strings("valid", []string{"path", "size"}))
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+
+	"fdups/finder"
+	"fdups/hasher"
+	"fdups/log"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// integrityFinders holds the finders whose hasher can check files against
+// their embedded checksums.
+var integrityFinders = []string{"flac"}
+
+// verifyReport is the output of the verify command.
+type verifyReport struct {
+	// Checked is the number of files checked.
+	Checked int `json:"checked"`
+	// Corrupt lists the files that failed the check.
+	Corrupt []finder.CorruptFile `json:"corrupt"`
+}
+
+// verifyCmd represents the verify command.
+var verifyCmd = &cobra.Command{
+	Use:   "verify <directory>",
+	Short: "Check files for corruption",
+	Long: "Scan a directory recursively, decode each file and check it against its embedded " +
+		"checksums. FLAC files are checked for frame CRC errors, truncation and audio MD5 " +
+		"mismatches.",
+	Args: cobra.ExactArgs(1),
+	Run:  runVerify,
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&finderType, "finder", "flac",
+		"Finder type: "+strings.Join(integrityFinders, ", "))
+	verifyCmd.Flags().Int64Var(&minSize, "min-size", 0, "Skip files smaller than this many bytes")
+	verifyCmd.Flags().Int64Var(&maxSize, "max-size", 0, "Skip files larger than this many bytes (0 for no limit)")
+	verifyCmd.Flags().BoolVar(&scanArchives, "archives", false,
+		"Also check the files inside zip, tar and tar.gz archives, reported as archive.zip!/member")
+	addWalkFlags(verifyCmd)
+
+	_ = verifyCmd.RegisterFlagCompletionFunc("finder",
+		cobra.FixedCompletions(integrityFinders, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.AddCommand(verifyCmd)
+}
+
+// runVerify is the main entry point for the verify command.
+func runVerify(cmd *cobra.Command, args []string) {
+	if !cmd.Flags().Changed("finder") {
+		finderType = "flac"
+	}
+	if !slices.Contains(integrityFinders, finderType) {
+		log.L().Fatal("Finder cannot check integrity",
+			zap.String("finder", finderType),
+			zap.Strings("valid", integrityFinders))
+	}
+
+	directory := resolveDirectory(args[0])
+	// Verifying decodes every file, even when --flac-md5 could otherwise
+	// return the MD5 recorded in STREAMINFO without decoding.
+	options := append(finderOptions(), finder.WithHasherOptions(hasher.WithVerify()))
+	f := createFinder(finderType, directory, options...)
+	result := executeFinder(f, directory)
+
+	report := verifyReport{Corrupt: []finder.CorruptFile{}}
+	for _, group := range result {
+		report.Checked += len(group)
+	}
+	if reporter, ok := f.(finder.DiagnosticsReporter); ok {
+		report.Corrupt = reporter.Diagnostics().Corrupt
+		report.Checked += len(report.Corrupt)
+	}
+	outputResult(report)
+}
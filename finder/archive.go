@@ -0,0 +1,211 @@
+package finder
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"fdups/log"
+
+	"go.uber.org/zap"
+)
+
+// archiveSeparator separates the path of an archive from the path of a
+// member within it in the Path of a virtual FileInfo.
+const archiveSeparator = "!/"
+
+// archiveVisitor receives the regular files of an archive in order. The
+// content reader is only valid until the visitor returns. Returning an error
+// stops listing the archive.
+type archiveVisitor func(name string, info os.FileInfo, content io.Reader) error
+
+// archiveLister reads the archive in file and passes its members to visit.
+type archiveLister func(file *os.File, visit archiveVisitor) error
+
+// WithArchives makes a Finder look inside zip, tar and gzip-compressed tar
+// archives, processing each regular file they contain as a virtual file
+// alongside the archive itself.
+//
+// Virtual files have Virtual set and a Path of the form
+// "backup.zip!/dir/file". They are hashed like other files but do not exist
+// on disk, so they can be reported but not acted upon. Archives nested in
+// archives are not opened.
+func WithArchives() Option {
+	return func(f *baseFinder) {
+		f.archives = true
+	}
+}
+
+// archiveListerFor returns the archiveLister for the archive at path, or nil
+// if path does not name a supported archive.
+func archiveListerFor(path string) archiveLister {
+	name := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return listZipMembers
+	case strings.HasSuffix(name, ".tar"):
+		return func(file *os.File, visit archiveVisitor) error {
+			return listTarMembers(file, visit)
+		}
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return listTarGzipMembers
+	default:
+		return nil
+	}
+}
+
+// walkArchive yields the members of the archive at archivePath accepted by
+// the file filter, if archives are enabled and the file is one.
+//
+// The content of each member is streamed to the worker hashing it through an
+// io.Pipe, so the archive is read only once and no member is held in memory.
+// The walker waits for each member to be read before moving on, so members
+// of an archive are hashed one at a time rather than by the whole worker
+// pool, and the walk does not continue past the archive until its last
+// member has been hashed. Archives that cannot be read are logged and
+// skipped. Returns errWalkStopped if Find returned in the meantime.
+func (f *baseFinder) walkArchive(archivePath string, channel chan<- walkDirectoryYield) error {
+	if !f.archives {
+		return nil
+	}
+	list := archiveListerFor(archivePath)
+	if list == nil {
+		return nil
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		log.L().Warn("Skipped archive (open failed)", zap.String("path", archivePath), zap.Error(err))
+		return nil
+	}
+	defer func() { _ = file.Close() }()
+
+	log.L().Debug("Opened archive", zap.String("path", archivePath))
+	err = list(file, func(name string, info os.FileInfo, content io.Reader) error {
+		return f.yieldArchiveMember(archivePath, name, info, content, channel)
+	})
+	if errors.Is(err, errWalkStopped) {
+		return err
+	}
+	if err != nil {
+		log.L().Warn("Stopped reading archive (read failed)", zap.String("path", archivePath), zap.Error(err))
+	}
+	return nil
+}
+
+// yieldArchiveMember yields a virtual FileInfo for an archive member and
+// streams its content until the worker hashing it is done reading.
+//
+// A worker may never read the content, when its task is cancelled or never
+// runs because Find returned early. The pipe is therefore also closed once
+// Find returns, and errWalkStopped returned.
+func (f *baseFinder) yieldArchiveMember(archivePath, name string, info os.FileInfo, content io.Reader, channel chan<- walkDirectoryYield) error {
+	memberPath := archivePath + archiveSeparator + strings.TrimPrefix(path.Clean("/"+name), "/")
+	if !f.fileFilter(memberPath, info) {
+		log.L().Debug("Skipped archive member (filtered)", zap.String("path", memberPath))
+		return nil
+	}
+
+	log.L().Debug("Discovered archive member", zap.String("path", memberPath))
+	reader, writer := io.Pipe()
+	err := f.yield(walkDirectoryYield{
+		fileInfo: &FileInfo{
+			Name:    path.Base(name),
+			Path:    memberPath,
+			Size:    info.Size(),
+			Mode:    posixMode(info.Mode()),
+			Virtual: true,
+		},
+		open: func() (io.ReadCloser, error) { return reader, nil },
+	}, channel)
+	if err != nil {
+		return err
+	}
+
+	copied := make(chan struct{})
+	defer close(copied)
+	go func() {
+		select {
+		case <-f.stopped:
+			_ = reader.CloseWithError(errWalkStopped)
+		case <-copied:
+		}
+	}()
+
+	// Hashers may stop reading early, closing the pipe, which is fine.
+	_, err = io.Copy(writer, content)
+	if errors.Is(err, errWalkStopped) {
+		return err
+	}
+	if err != nil && !errors.Is(err, io.ErrClosedPipe) {
+		log.L().Warn("Failed to read archive member", zap.String("path", memberPath), zap.Error(err))
+	}
+	_ = writer.CloseWithError(err)
+	return nil
+}
+
+// listZipMembers passes the regular files of a zip archive to visit.
+func listZipMembers(file *os.File, visit archiveVisitor) error {
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	archive, err := zip.NewReader(file, stat.Size())
+	if err != nil {
+		return err
+	}
+
+	for _, member := range archive.File {
+		info := member.FileInfo()
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		content, err := member.Open()
+		if err != nil {
+			return err
+		}
+		err = visit(member.Name, info, content)
+		_ = content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listTarMembers passes the regular files of a tar archive read from r to visit.
+func listTarMembers(r io.Reader, visit archiveVisitor) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := visit(header.Name, header.FileInfo(), archive); err != nil {
+			return err
+		}
+	}
+}
+
+// listTarGzipMembers passes the regular files of a gzip-compressed tar
+// archive to visit.
+func listTarGzipMembers(file *os.File, visit archiveVisitor) error {
+	decompressed, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = decompressed.Close() }()
+	return listTarMembers(decompressed, visit)
+}
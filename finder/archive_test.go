@@ -0,0 +1,124 @@
+package finder
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"fdups/hasher"
+)
+
+// zipMember is a file stored in a test zip archive.
+type zipMember struct {
+	name, content string
+}
+
+// writeZip creates a zip archive at path holding members in order.
+func writeZip(t *testing.T, path string, members ...zipMember) {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, member := range members {
+		w, err := archive.Create(member.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, member.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// failingHasher is the default hasher, failing on content "fail" with an
+// error that is not a format error and therefore aborts Find.
+type failingHasher struct {
+	hasher.Hasher
+}
+
+func (h failingHasher) Hash(r io.Reader) ([]byte, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if string(content) == "fail" {
+		return nil, errors.New("hash failed")
+	}
+	return h.Hasher.Hash(bytes.NewReader(content))
+}
+
+func TestWithArchivesGroupsMembersWithLooseFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"loose.txt": "duplicate", "other.txt": "unique"})
+	writeZip(t, filepath.Join(root, "backup.zip"),
+		zipMember{"dir/", ""},
+		zipMember{"dir/copy.txt", "duplicate"},
+		zipMember{"../escaped.txt", "escaped"},
+		zipMember{"member.txt", "member"})
+
+	result := find(t, NewDefaultFinder(root, WithArchives()))
+
+	want := [][]string{{"backup.zip!/dir/copy.txt", "loose.txt"}}
+	if got := groupedPaths(t, root, result); !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() groups = %v, want %v", got, want)
+	}
+	var members []FileInfo
+	for _, group := range result {
+		for _, fileInfo := range group {
+			if fileInfo.Virtual {
+				members = append(members, fileInfo)
+			}
+		}
+	}
+	wantMembers := []string{"backup.zip!/dir/copy.txt", "backup.zip!/escaped.txt", "backup.zip!/member.txt"}
+	if got := relativePaths(t, root, members); !reflect.DeepEqual(got, wantMembers) {
+		t.Errorf("Find() members = %v, want %v", got, wantMembers)
+	}
+}
+
+func TestWithArchivesStopsWalkingWhenFindFails(t *testing.T) {
+	root := t.TempDir()
+	members := []zipMember{{"fail", "fail"}}
+	for _, name := range strings.Split("abcdefghijklmnopqrstuvwxyz", "") {
+		members = append(members, zipMember{name, strings.Repeat(name, 1<<20)})
+	}
+	writeZip(t, filepath.Join(root, "backup.zip"), members...)
+
+	f := newBaseFinder(root, func(options ...hasher.Option) hasher.Hasher {
+		return failingHasher{hasher.NewDefaultHasher(options...)}
+	}, acceptAllFiles, []Option{WithArchives()})
+	walked := make(chan struct{})
+	walk := f.walker
+	f.walker = func() chan walkDirectoryYield {
+		channel := make(chan walkDirectoryYield)
+		go func() {
+			defer close(walked)
+			defer close(channel)
+			for item := range walk() {
+				channel <- item
+			}
+		}()
+		return channel
+	}
+
+	if err, _ := f.Find(); err == nil {
+		t.Fatal("Find() succeeded, want the hash error")
+	}
+	select {
+	case <-walked:
+	case <-time.After(10 * time.Second):
+		t.Fatal("walk did not stop after Find() returned")
+	}
+}
@@ -0,0 +1,61 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fdups/hasher"
+)
+
+// audioExtensions lists the extensions of the lossless audio files processed
+// by the audio finder.
+var audioExtensions = map[string]bool{
+	".flac": true,
+	".wav":  true,
+	".wave": true,
+	".aif":  true,
+	".aiff": true,
+	".aifc": true,
+	".m4a":  true,
+}
+
+func init() {
+	Register(Registration{
+		Name:        "audio",
+		Description: "Lossless audio files (FLAC, WAV, AIFF, ALAC), hashed by decoded audio across formats",
+		New:         NewAudioFinder,
+	})
+}
+
+// audioFinder finds duplicate lossless audio files across container formats.
+// It decodes each file to a canonical PCM representation, so the same audio
+// stored as FLAC and as WAV is detected as a duplicate.
+type audioFinder struct {
+	*baseFinder
+}
+
+// NewAudioFinder creates a Finder that processes FLAC, WAV, AIFF and ALAC
+// files in the target directory. Files named .m4a holding lossy audio are
+// reported as unparseable.
+//
+// Each file is decoded and compared by sample rate, channel count, bit depth
+// and samples alone, so a rip stored both as FLAC and as WAV is detected as
+// a duplicate. FLAC hashes differ from those of NewFlacFinder, as the
+// container-independent representation also covers the audio format.
+func NewAudioFinder(targetDirectory string, options ...Option) Finder {
+	return &audioFinder{
+		baseFinder: newBaseFinder(
+			targetDirectory,
+			hasher.NewAudioHasher,
+			acceptAudioFiles,
+			options,
+		),
+	}
+}
+
+// acceptAudioFiles is a FileFilter that accepts only lossless audio files
+// by extension (case-insensitive).
+func acceptAudioFiles(path string, _ os.FileInfo) bool {
+	return audioExtensions[strings.ToLower(filepath.Ext(path))]
+}
@@ -0,0 +1,78 @@
+package finder
+
+import (
+	"math/bits"
+)
+
+// bkTree is a BK-tree indexing 64-bit hashes by Hamming distance.
+//
+// Each child of a node is keyed by its distance to the node, so by the
+// triangle inequality a search for hashes within a radius only needs to
+// descend into children whose key is within the radius of the distance
+// between the query and the node.
+type bkTree struct {
+	root *bkNode
+}
+
+// bkNode is a node of a bkTree holding all items with the same hash.
+type bkNode struct {
+	hash     uint64
+	items    []int
+	children map[int]*bkNode
+}
+
+// hammingDistance returns the number of bits in which a and b differ.
+func hammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// insert adds item to the tree under hash.
+func (t *bkTree) insert(hash uint64, item int) {
+	if t.root == nil {
+		t.root = &bkNode{hash: hash, items: []int{item}}
+		return
+	}
+
+	node := t.root
+	for {
+		distance := hammingDistance(hash, node.hash)
+		if distance == 0 {
+			node.items = append(node.items, item)
+			return
+		}
+		child, exists := node.children[distance]
+		if !exists {
+			if node.children == nil {
+				node.children = make(map[int]*bkNode)
+			}
+			node.children[distance] = &bkNode{hash: hash, items: []int{item}}
+			return
+		}
+		node = child
+	}
+}
+
+// search calls visit for every item whose hash is within maxDistance of hash.
+func (t *bkTree) search(hash uint64, maxDistance int, visit func(item, distance int)) {
+	if t.root == nil {
+		return
+	}
+
+	pending := []*bkNode{t.root}
+	for len(pending) > 0 {
+		node := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		distance := hammingDistance(hash, node.hash)
+		if distance <= maxDistance {
+			for _, item := range node.items {
+				visit(item, distance)
+			}
+		}
+		for childDistance, child := range node.children {
+			if childDistance >= distance-maxDistance && childDistance <= distance+maxDistance {
+				pending = append(pending, child)
+			}
+		}
+	}
+}
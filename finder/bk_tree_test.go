@@ -0,0 +1,123 @@
+package finder
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestHammingDistance(t *testing.T) {
+	tests := []struct {
+		a, b     uint64
+		distance int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{0b1010, 0b0101, 4},
+		{0, ^uint64(0), 64},
+		{0xff00, 0x0ff0, 8},
+	}
+	for _, test := range tests {
+		if distance := hammingDistance(test.a, test.b); distance != test.distance {
+			t.Errorf("hammingDistance(%#x, %#x) = %d, want %d", test.a, test.b, distance, test.distance)
+		}
+	}
+}
+
+func TestBkTreeSearch(t *testing.T) {
+	hashes := []uint64{0, 1, 3, 7, 0xff, 0xff00, 0xffff, 1, ^uint64(0)}
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		hashes = append(hashes, random.Uint64()&0xffff)
+	}
+	tree := &bkTree{}
+	for i, hash := range hashes {
+		tree.insert(hash, i)
+	}
+
+	tests := []struct {
+		name        string
+		hash        uint64
+		maxDistance int
+	}{
+		{"exact match", 0xff, 0},
+		{"duplicate hashes", 1, 0},
+		{"small radius", 0, 2},
+		{"medium radius", 0xf0f0, 4},
+		{"no match", 0xffff_0000_0000_0000, 3},
+		{"everything", 0, 64},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var want []int
+			for i, hash := range hashes {
+				if hammingDistance(hash, test.hash) <= test.maxDistance {
+					want = append(want, i)
+				}
+			}
+
+			var got []int
+			tree.search(test.hash, test.maxDistance, func(item, distance int) {
+				if d := hammingDistance(hashes[item], test.hash); distance != d {
+					t.Errorf("search() reported distance %d for item %d, want %d", distance, item, d)
+				}
+				got = append(got, item)
+			})
+			sort.Ints(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("search(%#x, %d) = %v, want %v", test.hash, test.maxDistance, got, want)
+			}
+		})
+	}
+}
+
+func TestBkTreeSearchEmpty(t *testing.T) {
+	(&bkTree{}).search(0, 64, func(item, _ int) {
+		t.Errorf("search() of an empty tree visited item %d", item)
+	})
+}
+
+func TestClusterByDistance(t *testing.T) {
+	tests := []struct {
+		name        string
+		hashes      []uint64
+		maxDistance int
+		groups      [][]int
+	}{
+		{"no files", nil, 10, nil},
+		{"identical", []uint64{5, 5}, 0, [][]int{{0, 1}}},
+		{"too far apart", []uint64{0, 0b111}, 2, nil},
+		{"closest first", []uint64{0, 0b11, 0b1}, 2, [][]int{{0, 2, 1}}},
+		// 0 and 2 are both within reach of 1 but not of each other, so they
+		// are not chained into one group.
+		{"chain", []uint64{0, 0b11, 0b1111}, 2, [][]int{{0, 1}}},
+		{"separate groups", []uint64{0, 0xff00, 0b1, 0xff01}, 1, [][]int{{0, 2}, {1, 3}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := make([]FileInfo, len(test.hashes))
+			tree := &bkTree{}
+			for i, hash := range test.hashes {
+				files[i] = FileInfo{Path: string(rune('a' + i))}
+				tree.insert(hash, i)
+			}
+
+			var groups [][]int
+			for _, group := range clusterByDistance(files, test.hashes, tree, test.maxDistance) {
+				var members []int
+				for j, file := range group.Files {
+					members = append(members, int(file.Path[0]-'a'))
+					distance := hammingDistance(test.hashes[members[0]], test.hashes[members[j]])
+					if want := 1 - float64(distance)/perceptualHashBits; file.Similarity != want {
+						t.Errorf("similarity of %s = %v, want %v", file.Path, file.Similarity, want)
+					}
+				}
+				groups = append(groups, members)
+			}
+			if !reflect.DeepEqual(groups, test.groups) {
+				t.Errorf("clusterByDistance() = %v, want %v", groups, test.groups)
+			}
+		})
+	}
+}
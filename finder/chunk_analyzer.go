@@ -0,0 +1,207 @@
+package finder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"fdups/hasher"
+	"fdups/log"
+
+	"go.uber.org/zap"
+)
+
+// ChunkReport summarizes how much block-level deduplication would save.
+//
+// All byte counts refer to file content. TotalBytes is what is stored
+// without deduplication, FileUniqueBytes what remains after storing each
+// distinct file once, and UniqueBytes what remains after storing each
+// distinct chunk once.
+type ChunkReport struct {
+	// Method is the chunking method used.
+	Method string `json:"method"`
+	// Files is the number of files analyzed.
+	Files int `json:"files"`
+	// TotalBytes is the combined size of all files.
+	TotalBytes int64 `json:"totalBytes"`
+	// FileUniqueBytes is the combined size of all distinct files.
+	FileUniqueBytes int64 `json:"fileUniqueBytes"`
+	// UniqueBytes is the combined size of all distinct chunks.
+	UniqueBytes int64 `json:"uniqueBytes"`
+	// TotalChunks is the number of chunks in all files.
+	TotalChunks int `json:"totalChunks"`
+	// UniqueChunks is the number of distinct chunks.
+	UniqueChunks int `json:"uniqueChunks"`
+	// TopFiles lists the files sharing the most content with other files,
+	// most shared bytes first.
+	TopFiles []ChunkSharing `json:"topFiles"`
+}
+
+// ChunkSharing describes how many of a file's chunks occur in other files.
+type ChunkSharing struct {
+	// File is the analyzed file.
+	File FileInfo `json:"file"`
+	// Chunks is the number of chunks in the file.
+	Chunks int `json:"chunks"`
+	// SharedChunks is the number of the file's chunks also found in other files.
+	SharedChunks int `json:"sharedChunks"`
+	// SharedBytes is the combined size of the shared chunks.
+	SharedBytes int64 `json:"sharedBytes"`
+}
+
+// ChunkAnalyzer estimates the savings of block-level deduplication.
+type ChunkAnalyzer interface {
+	// Analyze scans the target directory, splits every file into chunks and
+	// reports the deduplication potential, listing the top files sharing
+	// the most chunks with other files.
+	//
+	// Returns an error if directory traversal or file processing fails.
+	Analyze(top int) (error, ChunkReport)
+}
+
+// chunkAnalyzer analyzes chunk-level duplication using the directory walking
+// and worker pool of a baseFinder.
+type chunkAnalyzer struct {
+	*baseFinder
+	method  hasher.ChunkingMethod
+	chunker hasher.Chunker
+	// chunkIndexes maps chunk hashes to their index in the chunk tables.
+	chunkIndexes map[string]int
+	// chunkSizes holds the size of each distinct chunk.
+	chunkSizes []int
+	// chunkFiles holds the number of files each distinct chunk occurs in.
+	chunkFiles []int
+	// chunkLastFile holds the index plus one of the last file each distinct
+	// chunk was found in, so repeated chunks within a file count once.
+	chunkLastFile []int
+	files         []chunkedFile
+}
+
+// chunkedFile is an analyzed file along with the indexes of its chunks.
+type chunkedFile struct {
+	fileInfo FileInfo
+	chunks   []int
+}
+
+// NewChunkAnalyzer creates a ChunkAnalyzer that splits all files in the
+// target directory into chunks using method.
+//
+// Files are walked and processed by a worker pool exactly like a Finder
+// would, and options such as WithFileFilter and WithArchives apply. Chunks
+// are digested with the algorithm selected through WithHasherOptions.
+func NewChunkAnalyzer(targetDirectory string, method hasher.ChunkingMethod, options ...Option) ChunkAnalyzer {
+	base := newBaseFinder(targetDirectory, hasher.NewDefaultHasher, acceptAllFiles, options)
+	a := &chunkAnalyzer{
+		baseFinder:   base,
+		method:       method,
+		chunker:      method.NewChunker(base.hasherOptions...),
+		chunkIndexes: make(map[string]int),
+	}
+	base.taskFunction = a.chunkTask
+	base.collect = a.collectChunks
+	return a
+}
+
+func (a *chunkAnalyzer) Analyze(top int) (error, ChunkReport) {
+	err, result := a.Find()
+	if err != nil {
+		return err, ChunkReport{}
+	}
+
+	report := ChunkReport{
+		Method:       string(a.method),
+		Files:        len(a.files),
+		UniqueChunks: len(a.chunkSizes),
+		TopFiles:     []ChunkSharing{},
+	}
+	for _, group := range result {
+		report.FileUniqueBytes += group[0].Size
+	}
+	for _, size := range a.chunkSizes {
+		report.UniqueBytes += int64(size)
+	}
+
+	for _, file := range a.files {
+		sharing := ChunkSharing{File: file.fileInfo, Chunks: len(file.chunks)}
+		for _, chunk := range file.chunks {
+			report.TotalBytes += int64(a.chunkSizes[chunk])
+			if a.chunkFiles[chunk] > 1 {
+				sharing.SharedChunks++
+				sharing.SharedBytes += int64(a.chunkSizes[chunk])
+			}
+		}
+		report.TotalChunks += sharing.Chunks
+		if sharing.SharedChunks > 0 {
+			report.TopFiles = append(report.TopFiles, sharing)
+		}
+	}
+
+	sort.Slice(report.TopFiles, func(i, j int) bool {
+		if report.TopFiles[i].SharedBytes != report.TopFiles[j].SharedBytes {
+			return report.TopFiles[i].SharedBytes > report.TopFiles[j].SharedBytes
+		}
+		return report.TopFiles[i].File.Path < report.TopFiles[j].File.Path
+	})
+	if len(report.TopFiles) > top {
+		report.TopFiles = report.TopFiles[:max(top, 0)]
+	}
+	return nil, report
+}
+
+// chunkTask is the task function of the analyzer, splitting the file of
+// input into chunks while hashing it as a whole.
+func (a *chunkAnalyzer) chunkTask(ctx context.Context, input taskInput) taskOutput {
+	if input.fileInfo == nil {
+		return taskOutput{}
+	}
+
+	log.L().Info("Chunking file", zap.String("name", input.fileInfo.Name))
+
+	file, err := openFile(ctx, input.fileInfo, input.open)
+	if err != nil {
+		return taskOutput{fileInfo: input.fileInfo, err: err}
+	}
+	defer func() { _ = file.Close() }()
+
+	hash := a.hasher.Algorithm().New()
+	var chunks []hasher.Chunk
+	err = a.chunker.Chunk(io.TeeReader(file, hash), func(chunk hasher.Chunk) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		return taskOutput{fileInfo: input.fileInfo, err: fmt.Errorf("failed to chunk %q: %w", input.fileInfo.Path, err)}
+	}
+
+	input.fileInfo.Hash = fmt.Sprintf("%x", hash.Sum(nil))
+	input.fileInfo.Algorithm = string(a.hasher.Algorithm())
+	input.fileInfo.Hasher = a.hasher.Name()
+	log.L().Debug("File chunked", zap.String("name", input.fileInfo.Name), zap.Int("chunks", len(chunks)))
+
+	return taskOutput{fileInfo: input.fileInfo, chunks: chunks}
+}
+
+// collectChunks records the chunks of a processed file in the chunk tables.
+func (a *chunkAnalyzer) collectChunks(output taskOutput) {
+	fileNumber := len(a.files) + 1
+	file := chunkedFile{fileInfo: *output.fileInfo, chunks: make([]int, 0, len(output.chunks))}
+
+	for _, chunk := range output.chunks {
+		index, exists := a.chunkIndexes[string(chunk.Hash)]
+		if !exists {
+			index = len(a.chunkSizes)
+			a.chunkIndexes[string(chunk.Hash)] = index
+			a.chunkSizes = append(a.chunkSizes, chunk.Size)
+			a.chunkFiles = append(a.chunkFiles, 0)
+			a.chunkLastFile = append(a.chunkLastFile, 0)
+		}
+		if a.chunkLastFile[index] != fileNumber {
+			a.chunkLastFile[index] = fileNumber
+			a.chunkFiles[index]++
+		}
+		file.chunks = append(file.chunks, index)
+	}
+
+	a.files = append(a.files, file)
+	a.groupDuplicates(*output.fileInfo)
+}
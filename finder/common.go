@@ -1,12 +1,16 @@
 package finder
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
 
 	"fdups/hasher"
 	"fdups/log"
@@ -18,20 +22,34 @@ import (
 // taskInput is the input type for hash computation tasks.
 type taskInput struct {
 	fileInfo *FileInfo
+	open     contentOpener
 }
 
 // taskOutput is the output type for hash computation tasks.
 type taskOutput struct {
 	fileInfo *FileInfo
 	err      error
+	chunks   []hasher.Chunk
 }
 
 // walkDirectoryYield represents a result from directory traversal.
 type walkDirectoryYield struct {
 	err      error
 	fileInfo *FileInfo
+	open     contentOpener
 }
 
+// contentOpener opens the content of a file that cannot be opened by its
+// path, such as an archive member. Files without one are opened by path.
+type contentOpener func() (io.ReadCloser, error)
+
+// HasherConstructor creates a hasher.Hasher configured with options.
+type HasherConstructor func(options ...hasher.Option) hasher.Hasher
+
+// hasherSelector chooses the hasher for a file given the leading bytes of
+// its content, which are at most sniffLength bytes long.
+type hasherSelector func(fileInfo *FileInfo, head []byte) hasher.Hasher
+
 // FileFilter is a predicate function that determines if a file should be processed.
 // It receives the file path and os.FileInfo, returning true if the file should be included.
 type FileFilter func(path string, info os.FileInfo) bool
@@ -42,21 +60,75 @@ type FileFilter func(path string, info os.FileInfo) bool
 type baseFinder struct {
 	targetDirectory string
 	workerPool      pool.WorkerPool[taskInput, taskOutput]
+	taskFunction    pool.TaskFunction[taskInput, taskOutput]
+	collect         func(output taskOutput)
 	result          map[string][]FileInfo
 	hasher          hasher.Hasher
 	fileFilter      FileFilter
+	walker          func() chan walkDirectoryYield
+	hasherOptions   []hasher.Option
+	routes          []Route
+	selectHasher    hasherSelector
+	diagnostics     Diagnostics
+	// diagnosticsMutex guards diagnostics.Unreadable, which both the walking
+	// and the collecting goroutine append to.
+	diagnosticsMutex sync.Mutex
+	maxDistance      int
+	minSimilarity    float64
+	archives         bool
+	symlinks         SymlinkPolicy
+	// visitedDirectories holds the directories walked so far when
+	// following symlinks.
+	visitedDirectories map[inodeIdentity]bool
+	// visitedFiles holds the files processed so far when following
+	// symlinks, mapped to whether they were reached through a link.
+	visitedFiles    map[inodeIdentity]bool
+	oneFileSystem   bool
+	excludedFsTypes []string
+	// excludedDevices maps the devices of excluded filesystems to their type.
+	excludedDevices map[uint64]string
+	rootDevice      uint64
+	rootDeviceKnown bool
+	// directoryWorkers is the number of directories read concurrently by
+	// directoryReader while walking.
+	directoryWorkers int
+	directoryReader  *directoryReader
+	// stopped is closed once Find returns, telling the walking goroutine to
+	// stop yielding files no worker will hash.
+	stopped chan struct{}
 }
 
 // newBaseFinder creates a new baseFinder with the specified configuration.
-// The worker pool is sized to the number of available CPU cores.
-func newBaseFinder(targetDirectory string, h hasher.Hasher, filter FileFilter) *baseFinder {
-	return &baseFinder{
+// The worker pool is sized to the number of available CPU cores, and the
+// hasher is created once all options have been applied. Each file is hashed
+// and grouped with its duplicates unless the finder replaces taskFunction
+// and collect.
+func newBaseFinder(targetDirectory string, newHasher HasherConstructor, filter FileFilter, options []Option) *baseFinder {
+	f := &baseFinder{
 		targetDirectory: targetDirectory,
 		workerPool:      pool.NewDefaultWorkerPool[taskInput, taskOutput](runtime.NumCPU()),
 		result:          make(map[string][]FileInfo),
-		hasher:          h,
 		fileFilter:      filter,
+		diagnostics: Diagnostics{
+			Corrupt:     []CorruptFile{},
+			Symlinks:    []Symlink{},
+			Dangling:    []Symlink{},
+			Special:     []SpecialFile{},
+			Unreadable:  []UnreadableFile{},
+			Unparseable: []UnparseableFile{},
+		},
+		symlinks:         SkipSymlinks,
+		directoryWorkers: DefaultDirectoryWorkers,
+		stopped:          make(chan struct{}),
+	}
+	f.walker = f.walkDirectory
+	f.taskFunction = f.hashTask
+	f.collect = func(output taskOutput) { f.groupDuplicates(*output.fileInfo) }
+	for _, option := range options {
+		option(f)
 	}
+	f.hasher = newHasher(f.hasherOptions...)
+	return f
 }
 
 func (f *baseFinder) Find() (error, map[string][]FileInfo) {
@@ -64,9 +136,10 @@ func (f *baseFinder) Find() (error, map[string][]FileInfo) {
 	log.L().Debug("Worker pool started")
 	defer f.stopWorkerPool()
 
-	errorChannel := make(chan error)
+	// Both goroutines send exactly once, and may do so after Find returned
+	// early on the error of the other.
+	errorChannel := make(chan error, 2)
 	log.L().Debug("Error handling channel created")
-	defer close(errorChannel)
 
 	go f.runWalkGoroutine(errorChannel)
 	go f.runCollectGoroutine(errorChannel)
@@ -75,42 +148,58 @@ func (f *baseFinder) Find() (error, map[string][]FileInfo) {
 }
 
 func (f *baseFinder) stopWorkerPool() {
+	close(f.stopped)
 	f.workerPool.Stop()
 	log.L().Debug("Worker pool stopped")
 }
 
+// yield passes item to the goroutine submitting hash tasks. Returns
+// errWalkStopped instead if Find returned in the meantime, so the walk ends
+// rather than blocking forever.
+func (f *baseFinder) yield(item walkDirectoryYield, channel chan<- walkDirectoryYield) error {
+	select {
+	case channel <- item:
+		return nil
+	case <-f.stopped:
+		return errWalkStopped
+	}
+}
+
 func (f *baseFinder) runWalkGoroutine(errorChannel chan<- error) {
-	for item := range f.walkDirectory() {
+	for item := range f.walker() {
 		if item.err != nil {
 			log.L().Error("Error received, aborting", zap.Error(item.err))
 			errorChannel <- item.err
 			return
 		}
-		f.submitHashTask(item.fileInfo)
+		f.submitHashTask(item)
 	}
 	f.workerPool.CloseSubmit()
 	log.L().Debug("All task submitted; Goroutine exit")
 	errorChannel <- nil
 }
 
-func (f *baseFinder) submitHashTask(fileInfo *FileInfo) {
+func (f *baseFinder) submitHashTask(item walkDirectoryYield) {
 	_ = f.workerPool.Submit(pool.Task[taskInput, taskOutput]{
-		TaskFunction: f.createHashFunction(),
-		Input:        taskInput{fileInfo: fileInfo},
+		TaskFunction: f.taskFunction,
+		Input:        taskInput{fileInfo: item.fileInfo, open: item.open},
 	})
-	log.L().Debug("Hashing task submitted", zap.String("name", fileInfo.Name))
+	log.L().Debug("Hashing task submitted", zap.String("name", item.fileInfo.Name))
 }
 
 func (f *baseFinder) runCollectGoroutine(errorChannel chan<- error) {
 	for {
 		select {
 		case item := <-f.workerPool.GetOutputChannel():
+			if item.err != nil && (f.recordIntegrityError(item.fileInfo, item.err) || f.recordFormatError(item.fileInfo, item.err) || f.recordOpenPermissionError(item.fileInfo, item.err)) {
+				continue
+			}
 			if item.err != nil {
 				log.L().Error("Error received, aborting", zap.Error(item.err))
 				errorChannel <- item.err
 				return
 			}
-			f.groupDuplicates(*item.fileInfo)
+			f.collect(item)
 		case event := <-f.workerPool.GetEventChannel():
 			if event == pool.EventAllTaskDone {
 				log.L().Debug("All task processed; Goroutine exit")
@@ -137,73 +226,151 @@ func (f *baseFinder) walkDirectory() chan walkDirectoryYield {
 
 	go func() {
 		defer close(channel)
-		_ = filepath.Walk(f.targetDirectory, func(path string, info os.FileInfo, err error) error {
-			return f.processWalkEntry(path, info, err, channel)
-		})
+		if err := f.loadExcludedDevices(); err != nil {
+			_ = f.handleWalkError(f.targetDirectory, err, channel)
+			return
+		}
+		f.directoryReader = newDirectoryReader(f.directoryWorkers)
+		defer f.directoryReader.close()
+		_ = f.walkTree(f.targetDirectory, channel)
 	}()
 
 	return channel
 }
 
 func (f *baseFinder) processWalkEntry(path string, info os.FileInfo, err error, channel chan<- walkDirectoryYield) error {
+	if err != nil && path != f.targetDirectory && errors.Is(err, fs.ErrPermission) {
+		// Unreadable entries below the target directory are reported instead
+		// of aborting the walk; returning nil skips an unreadable directory.
+		f.recordUnreadable(path, err)
+		return nil
+	}
 	if err != nil {
 		return f.handleWalkError(path, err, channel)
 	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return f.processSymlink(path, channel)
+	}
 	if info.IsDir() {
+		if !f.onWalkedFilesystem(path, info) || !f.enterDirectory(path, info) {
+			return filepath.SkipDir
+		}
 		log.L().Debug("Discovered directory", zap.String("name", info.Name()))
 		return nil
 	}
+	if info.Mode().IsRegular() && !f.claimFile(path, info, false) {
+		return nil
+	}
+	return f.processFile(path, info, channel)
+}
+
+// processFile yields the file at path, described by info, which is neither a
+// directory nor a symbolic link, along with the members of archives.
+func (f *baseFinder) processFile(path string, info os.FileInfo, channel chan<- walkDirectoryYield) error {
+	if !info.Mode().IsRegular() {
+		f.recordSpecialFile(path, info)
+		return nil
+	}
 	if !f.fileFilter(path, info) {
 		log.L().Debug("Skipped file (filtered)", zap.String("name", info.Name()))
-		return nil
+	} else {
+		log.L().Debug("Discovered file", zap.String("name", info.Name()))
+		if err := f.yield(walkDirectoryYield{fileInfo: newFileInfo(path, info)}, channel); err != nil {
+			return err
+		}
 	}
-	log.L().Debug("Discovered file", zap.String("name", info.Name()))
-	channel <- walkDirectoryYield{nil, &FileInfo{
-		Name: info.Name(),
-		Path: path,
-		Size: info.Size(),
-		Hash: "",
-	}}
-	return nil
+	return f.walkArchive(path, channel)
+}
+
+// newFileInfo creates a FileInfo for the file at path without a hash.
+func newFileInfo(path string, info os.FileInfo) *FileInfo {
+	device, inode, _ := fileIdentity(info)
+	fileInfo := &FileInfo{
+		Name:   info.Name(),
+		Path:   path,
+		Size:   info.Size(),
+		Hash:   "",
+		Device: device,
+		Inode:  inode,
+		Mode:   posixMode(info.Mode()),
+	}
+	if uid, gid, ok := fileOwner(info); ok {
+		fileInfo.UID, fileInfo.GID = &uid, &gid
+	}
+	return fileInfo
 }
 
 func (f *baseFinder) handleWalkError(path string, err error, channel chan<- walkDirectoryYield) error {
 	wrappedErr := errors.Join(err, fmt.Errorf("error accessing path %q", path))
-	channel <- walkDirectoryYield{wrappedErr, nil}
+	_ = f.yield(walkDirectoryYield{err: wrappedErr}, channel)
 	return wrappedErr
 }
 
 func (f *baseFinder) groupDuplicates(fileInfo FileInfo) {
-	existing, exists := f.result[fileInfo.Hash]
+	key := fileInfo.GroupKey()
+	existing, exists := f.result[key]
 	if exists {
-		log.L().Debug("Found duplicate", zap.String("hash", fileInfo.Hash))
+		log.L().Debug("Found duplicate", zap.String("hash", key))
 	}
-	f.result[fileInfo.Hash] = append(existing, fileInfo)
+	f.result[key] = append(existing, fileInfo)
 }
 
-func (f *baseFinder) createHashFunction() pool.TaskFunction[taskInput, taskOutput] {
-	return func(ctx context.Context, input taskInput) taskOutput {
-		if input.fileInfo == nil {
-			return taskOutput{nil, nil}
-		}
+// hashTask is the default task function, hashing the file of input.
+func (f *baseFinder) hashTask(ctx context.Context, input taskInput) taskOutput {
+	if input.fileInfo == nil {
+		return taskOutput{}
+	}
 
-		log.L().Info("Calculating hash", zap.String("name", input.fileInfo.Name))
+	log.L().Info("Calculating hash", zap.String("name", input.fileInfo.Name))
 
-		hash, err := f.hashFile(ctx, input.fileInfo)
-		if err != nil {
-			return taskOutput{input.fileInfo, err}
-		}
+	hash, h, err := f.hashFile(ctx, input.fileInfo, input.open)
+	if err != nil {
+		return taskOutput{fileInfo: input.fileInfo, err: err}
+	}
+
+	input.fileInfo.Hash = fmt.Sprintf("%x", hash)
+	input.fileInfo.Algorithm = string(h.Algorithm())
+	input.fileInfo.Hasher = h.Name()
+	log.L().Debug("Hash calculated",
+		zap.String("name", input.fileInfo.Name),
+		zap.String("hash", input.fileInfo.Hash))
+
+	return taskOutput{fileInfo: input.fileInfo}
+}
+
+// hashFile hashes the file described by fileInfo, returning the hash
+// along with the hasher that computed it. The file is opened with open if
+// it is set, and by path otherwise.
+func (f *baseFinder) hashFile(ctx context.Context, fileInfo *FileInfo, open contentOpener) ([]byte, hasher.Hasher, error) {
+	file, err := openFile(ctx, fileInfo, open)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = file.Close()
+		log.L().Debug("Closed file", zap.String("name", fileInfo.Name))
+	}()
 
-		input.fileInfo.Hash = fmt.Sprintf("%x", hash)
-		log.L().Debug("Hash calculated",
-			zap.String("name", input.fileInfo.Name),
-			zap.String("hash", input.fileInfo.Hash))
+	h, r := f.hasher, io.Reader(file)
+	if f.selectHasher != nil {
+		buffered := bufio.NewReaderSize(file, sniffLength)
+		// A short or failed peek leaves head incomplete; read errors
+		// resurface when the hasher consumes the reader.
+		head, _ := buffered.Peek(sniffLength)
+		h, r = f.selectHasher(fileInfo, head), buffered
+		log.L().Debug("Hasher selected", zap.String("name", fileInfo.Name), zap.String("hasher", h.Name()))
+	}
 
-		return taskOutput{input.fileInfo, nil}
+	hash, err := h.Hash(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to hash %q: %w", fileInfo.Path, err)
 	}
+	return hash, h, nil
 }
 
-func (f *baseFinder) hashFile(ctx context.Context, fileInfo *FileInfo) ([]byte, error) {
+// openFile opens the file described by fileInfo for a task running under
+// ctx. The file is opened with open if it is set, and by path otherwise.
+func openFile(ctx context.Context, fileInfo *FileInfo, open contentOpener) (io.ReadCloser, error) {
 	select {
 	case <-ctx.Done():
 		log.L().Debug("Task function received cancelled signal")
@@ -211,19 +378,13 @@ func (f *baseFinder) hashFile(ctx context.Context, fileInfo *FileInfo) ([]byte,
 	default:
 	}
 
-	file, err := os.Open(fileInfo.Path)
+	if open == nil {
+		open = func() (io.ReadCloser, error) { return os.Open(fileInfo.Path) }
+	}
+	file, err := open()
 	if err != nil {
 		return nil, fmt.Errorf("failed to open %q: %w", fileInfo.Path, err)
 	}
 	log.L().Debug("Opened file", zap.String("name", fileInfo.Name))
-	defer func() {
-		_ = file.Close()
-		log.L().Debug("Closed file", zap.String("name", fileInfo.Name))
-	}()
-
-	hash, err := f.hasher.Hash(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to hash %q: %w", fileInfo.Path, err)
-	}
-	return hash, nil
+	return file, nil
 }
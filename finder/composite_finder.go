@@ -0,0 +1,139 @@
+package finder
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"fdups/hasher"
+)
+
+// sniffLength is the number of leading bytes inspected to detect MIME types.
+const sniffLength = 512
+
+func init() {
+	Register(Registration{
+		Name:        "mixed",
+		Description: "All files, FLAC hashed by decoded audio and the rest by raw content",
+		New: func(targetDirectory string, options ...Option) Finder {
+			return NewCompositeFinder(targetDirectory, defaultRoutes(), hasher.NewDefaultHasher, options...)
+		},
+	})
+}
+
+// Route directs matching files to a hasher in a composite finder.
+//
+// A file matches if its extension is listed in Extensions or the MIME type
+// sniffed from its content is listed in MIMETypes.
+type Route struct {
+	// Extensions lists file extensions including the leading dot.
+	// They are matched case-insensitively.
+	Extensions []string
+	// MIMETypes lists MIME types without parameters, such as "audio/flac".
+	MIMETypes []string
+	// Hasher creates the hasher for matching files.
+	Hasher HasherConstructor
+}
+
+// compiledRoute is a Route with its hasher created and its matchers indexed.
+type compiledRoute struct {
+	extensions map[string]bool
+	mimeTypes  map[string]bool
+	hasher     hasher.Hasher
+}
+
+// compositeFinder finds duplicate files of mixed types by routing each file
+// to a hasher suited to its type.
+type compositeFinder struct {
+	*baseFinder
+	routes   []compiledRoute
+	sniffing bool
+}
+
+// NewCompositeFinder creates a Finder that processes all files in the target
+// directory, hashing each with the hasher of the first matching route.
+// Files matching no route are hashed by the hasher fallback creates.
+//
+// Routes given with WithRoutes take precedence over routes. Every file's
+// group key includes the name of its hasher, so files hashed by different
+// hashers never end up in the same group.
+func NewCompositeFinder(targetDirectory string, routes []Route, fallback HasherConstructor, options ...Option) Finder {
+	base := newBaseFinder(targetDirectory, fallback, acceptAllFiles, options)
+	f := &compositeFinder{baseFinder: base}
+	for _, route := range append(base.routes, routes...) {
+		f.addRoute(route)
+	}
+	base.selectHasher = f.selectHasher
+	return f
+}
+
+// WithRoutes adds routes to a composite finder, taking precedence over its
+// default routes. Other finders ignore them.
+func WithRoutes(routes ...Route) Option {
+	return func(f *baseFinder) {
+		f.routes = append(f.routes, routes...)
+	}
+}
+
+// defaultRoutes returns the routes of the registered "mixed" finder.
+func defaultRoutes() []Route {
+	return []Route{{
+		Extensions: []string{".flac"},
+		MIMETypes:  []string{"audio/flac"},
+		Hasher:     hasher.NewFlacHasher,
+	}}
+}
+
+func (f *compositeFinder) addRoute(route Route) {
+	compiled := compiledRoute{
+		extensions: make(map[string]bool),
+		mimeTypes:  make(map[string]bool),
+		hasher:     route.Hasher(f.hasherOptions...),
+	}
+	for _, extension := range route.Extensions {
+		compiled.extensions[strings.ToLower(extension)] = true
+	}
+	for _, mimeType := range route.MIMETypes {
+		compiled.mimeTypes[mimeType] = true
+		f.sniffing = true
+	}
+	f.routes = append(f.routes, compiled)
+}
+
+// selectHasher returns the hasher of the first route matching the file,
+// trying extensions before sniffing the content.
+func (f *compositeFinder) selectHasher(fileInfo *FileInfo, head []byte) hasher.Hasher {
+	extension := strings.ToLower(filepath.Ext(fileInfo.Path))
+	for _, route := range f.routes {
+		if route.extensions[extension] {
+			return route.hasher
+		}
+	}
+
+	if f.sniffing {
+		mimeType := sniffMIMEType(head)
+		for _, route := range f.routes {
+			if route.mimeTypes[mimeType] {
+				return route.hasher
+			}
+		}
+	}
+	return f.hasher
+}
+
+// sniffMIMEType detects the MIME type of content from its leading bytes.
+//
+// It recognizes a few formats unknown to http.DetectContentType before
+// deferring to it, and strips any parameters from the result.
+func sniffMIMEType(head []byte) string {
+	if bytes.HasPrefix(head, []byte("fLaC")) {
+		return "audio/flac"
+	}
+	mimeType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mimeType
+}
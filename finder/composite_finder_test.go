@@ -0,0 +1,83 @@
+package finder
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+
+	"fdups/hasher"
+)
+
+// flacFile returns a mono 16-bit FLAC stream holding samples in a single
+// verbatim frame. FLAC requires at least 16 samples per frame.
+func flacFile(t *testing.T, samples []int32) string {
+	t.Helper()
+	info := &meta.StreamInfo{
+		BlockSizeMin:  uint16(len(samples)),
+		BlockSizeMax:  uint16(len(samples)),
+		SampleRate:    44100,
+		NChannels:     1,
+		BitsPerSample: 16,
+		NSamples:      uint64(len(samples)),
+	}
+	var buf bytes.Buffer
+	enc, err := flac.NewEncoder(&buf, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc.EnablePredictionAnalysis(false)
+	f := &frame.Frame{
+		Header: frame.Header{
+			HasFixedBlockSize: true,
+			BlockSize:         uint16(len(samples)),
+			SampleRate:        44100,
+			Channels:          frame.ChannelsMono,
+			BitsPerSample:     16,
+		},
+		Subframes: []*frame.Subframe{{
+			SubHeader: frame.SubHeader{Pred: frame.PredVerbatim},
+			Samples:   samples,
+			NSamples:  len(samples),
+		}},
+	}
+	if err := enc.WriteFrame(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestMixedFinderReportsInvalidFlacAsUnparseable(t *testing.T) {
+	root := t.TempDir()
+	samples := make([]int32, 64)
+	for i := range samples {
+		samples[i] = int32(i*i) - 2000
+	}
+	audio := flacFile(t, samples)
+	writeTree(t, root, map[string]string{
+		"a.flac":      audio,
+		"b.flac":      audio,
+		"broken.flac": "not a FLAC stream",
+		"x.txt":       "text",
+		"y.txt":       "text",
+	})
+
+	f := NewCompositeFinder(root, defaultRoutes(), hasher.NewDefaultHasher)
+	result := find(t, f)
+
+	wantGroups := [][]string{{"a.flac", "b.flac"}, {"x.txt", "y.txt"}}
+	if got := groupedPaths(t, root, result); !reflect.DeepEqual(got, wantGroups) {
+		t.Errorf("Find() groups = %v, want %v", got, wantGroups)
+	}
+	unparseable := f.(DiagnosticsReporter).Diagnostics().Unparseable
+	if len(unparseable) != 1 || unparseable[0].Path != filepath.Join(root, "broken.flac") || unparseable[0].Format != "FLAC" {
+		t.Errorf("Diagnostics().Unparseable = %+v, want broken.flac as invalid FLAC", unparseable)
+	}
+}
@@ -6,22 +6,32 @@ import (
 	"fdups/hasher"
 )
 
-// defaultFinder finds duplicate files by computing SHA-256 hashes
-// of raw file content. It processes all files regardless of type.
+func init() {
+	Register(Registration{
+		Name:        "default",
+		Description: "All files, hashed by raw content",
+		New:         NewDefaultFinder,
+	})
+}
+
+// defaultFinder finds duplicate files by hashing raw file content.
+// It processes all files regardless of type.
 type defaultFinder struct {
 	*baseFinder
 }
 
 // NewDefaultFinder creates a Finder that processes all files in the
-// target directory using SHA-256 hashing.
+// target directory by hashing their raw content, using SHA-256 unless
+// another algorithm is selected with WithHasherOptions.
 //
 // This is the general-purpose finder suitable for any file type.
-func NewDefaultFinder(targetDirectory string) Finder {
+func NewDefaultFinder(targetDirectory string, options ...Option) Finder {
 	return &defaultFinder{
 		baseFinder: newBaseFinder(
 			targetDirectory,
-			hasher.NewDefaultHasher(),
+			hasher.NewDefaultHasher,
 			acceptAllFiles,
+			options,
 		),
 	}
 }
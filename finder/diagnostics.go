@@ -0,0 +1,175 @@
+package finder
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+
+	"fdups/hasher"
+	"fdups/log"
+
+	"go.uber.org/zap"
+)
+
+// CorruptFile describes a file that failed an integrity check.
+type CorruptFile struct {
+	// File is the corrupt file. Its hash is not set.
+	File FileInfo `json:"file"`
+	// Failure classifies the corruption.
+	Failure hasher.IntegrityFailure `json:"failure"`
+	// Error describes the corruption.
+	Error string `json:"error"`
+}
+
+// SpecialFile describes a file that is neither a regular file, a directory
+// nor a symbolic link, and is therefore not hashed.
+type SpecialFile struct {
+	// Path is the path of the file.
+	Path string `json:"path"`
+	// Type is the kind of file: fifo, socket, char-device, block-device or
+	// irregular.
+	Type string `json:"type"`
+}
+
+// UnreadableFile describes a file or directory that could not be read for
+// lack of permission.
+type UnreadableFile struct {
+	// Path is the path of the file or directory.
+	Path string `json:"path"`
+	// Error describes the failure.
+	Error string `json:"error"`
+}
+
+// UnparseableFile describes a file that is not valid in the format its
+// hasher expects, and is therefore not hashed.
+type UnparseableFile struct {
+	// Path is the path of the file.
+	Path string `json:"path"`
+	// Format is the format the hasher expected.
+	Format string `json:"format"`
+	// Error describes the failure.
+	Error string `json:"error"`
+}
+
+// Diagnostics holds the files a Finder kept out of its result, with the
+// reason for each.
+type Diagnostics struct {
+	// Corrupt lists files that failed an integrity check.
+	Corrupt []CorruptFile `json:"corrupt"`
+	// Symlinks lists symbolic links that were reported instead of followed.
+	Symlinks []Symlink `json:"symlinks"`
+	// Dangling lists symbolic links whose target could not be resolved.
+	Dangling []Symlink `json:"dangling"`
+	// Special lists files that are not regular files, such as FIFOs,
+	// sockets and device nodes.
+	Special []SpecialFile `json:"special"`
+	// Unreadable lists files and directories that could not be read for
+	// lack of permission.
+	Unreadable []UnreadableFile `json:"unreadable"`
+	// Unparseable lists files that are not valid in the format their hasher
+	// expects, such as truncated images or MP3 files without audio frames.
+	Unparseable []UnparseableFile `json:"unparseable"`
+}
+
+// DiagnosticsReporter is implemented by finders that report the files they
+// kept out of their result. Diagnostics is valid once Find has returned.
+type DiagnosticsReporter interface {
+	Diagnostics() Diagnostics
+}
+
+func (f *baseFinder) Diagnostics() Diagnostics {
+	corrupt := f.diagnostics.Corrupt
+	sort.Slice(corrupt, func(i, j int) bool { return corrupt[i].File.Path < corrupt[j].File.Path })
+	sortSymlinks(f.diagnostics.Symlinks)
+	sortSymlinks(f.diagnostics.Dangling)
+	special := f.diagnostics.Special
+	sort.Slice(special, func(i, j int) bool { return special[i].Path < special[j].Path })
+	unreadable := f.diagnostics.Unreadable
+	sort.Slice(unreadable, func(i, j int) bool { return unreadable[i].Path < unreadable[j].Path })
+	unparseable := f.diagnostics.Unparseable
+	sort.Slice(unparseable, func(i, j int) bool { return unparseable[i].Path < unparseable[j].Path })
+	return f.diagnostics
+}
+
+// recordIntegrityError records fileInfo as corrupt if err is an
+// *hasher.IntegrityError. It reports whether err was recorded.
+func (f *baseFinder) recordIntegrityError(fileInfo *FileInfo, err error) bool {
+	var integrityErr *hasher.IntegrityError
+	if fileInfo == nil || !errors.As(err, &integrityErr) {
+		return false
+	}
+
+	log.L().Warn("Corrupt file excluded", zap.String("path", fileInfo.Path), zap.Error(err))
+	f.diagnostics.Corrupt = append(f.diagnostics.Corrupt, CorruptFile{
+		File:    *fileInfo,
+		Failure: integrityErr.Failure,
+		Error:   err.Error(),
+	})
+	return true
+}
+
+// recordFormatError records fileInfo as unparseable if err is a
+// *hasher.FormatError. It reports whether err was recorded.
+func (f *baseFinder) recordFormatError(fileInfo *FileInfo, err error) bool {
+	var formatErr *hasher.FormatError
+	if fileInfo == nil || !errors.As(err, &formatErr) {
+		return false
+	}
+
+	log.L().Warn("Unparseable file excluded", zap.String("path", fileInfo.Path), zap.Error(err))
+	f.diagnostics.Unparseable = append(f.diagnostics.Unparseable, UnparseableFile{
+		Path:   fileInfo.Path,
+		Format: formatErr.Format,
+		Error:  err.Error(),
+	})
+	return true
+}
+
+// sortSymlinks sorts symlinks by path.
+func sortSymlinks(symlinks []Symlink) {
+	sort.Slice(symlinks, func(i, j int) bool { return symlinks[i].Path < symlinks[j].Path })
+}
+
+// recordSpecialFile records the file described by info as special.
+func (f *baseFinder) recordSpecialFile(path string, info os.FileInfo) {
+	fileType := specialFileType(info.Mode())
+	log.L().Info("Skipped special file", zap.String("path", path), zap.String("type", fileType))
+	f.diagnostics.Special = append(f.diagnostics.Special, SpecialFile{Path: path, Type: fileType})
+}
+
+// specialFileType names the kind of a file that is not a regular file,
+// directory or symbolic link.
+func specialFileType(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeCharDevice != 0:
+		return "char-device"
+	case mode&os.ModeDevice != 0:
+		return "block-device"
+	default:
+		return "irregular"
+	}
+}
+
+// recordUnreadable records the file or directory at path as unreadable.
+func (f *baseFinder) recordUnreadable(path string, err error) {
+	log.L().Warn("Skipped unreadable path", zap.String("path", path), zap.Error(err))
+	f.diagnosticsMutex.Lock()
+	defer f.diagnosticsMutex.Unlock()
+	f.diagnostics.Unreadable = append(f.diagnostics.Unreadable, UnreadableFile{Path: path, Error: err.Error()})
+}
+
+// recordOpenPermissionError records fileInfo as unreadable if err reports
+// that the file could not be opened for lack of permission. It reports
+// whether err was recorded.
+func (f *baseFinder) recordOpenPermissionError(fileInfo *FileInfo, err error) bool {
+	if fileInfo == nil || fileInfo.Virtual || !errors.Is(err, fs.ErrPermission) {
+		return false
+	}
+	f.recordUnreadable(fileInfo.Path, err)
+	return true
+}
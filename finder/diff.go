@@ -0,0 +1,96 @@
+package finder
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// RelocatedFile pairs a source file with the destination files holding the
+// same content under a different relative path.
+type RelocatedFile struct {
+	// Source is the file in the source tree.
+	Source FileInfo `json:"source"`
+	// Destinations are the files in the destination tree with the same content.
+	Destinations []FileInfo `json:"destinations"`
+}
+
+// DiffResult holds the differences between two hashed directory trees.
+type DiffResult struct {
+	// OnlyInSource lists source files whose content exists nowhere in the destination.
+	OnlyInSource []FileInfo `json:"onlyInSource"`
+	// OnlyInDestination lists destination files whose content exists nowhere in the source.
+	OnlyInDestination []FileInfo `json:"onlyInDestination"`
+	// Relocated lists source files whose content exists in the destination
+	// only under a different relative path.
+	Relocated []RelocatedFile `json:"relocated"`
+}
+
+// Diff compares the Find results of a source and a destination tree.
+//
+// Files are matched by content hash. A file present in both trees is
+// reported as relocated when none of its copies in the destination share
+// its path relative to sourceRoot.
+func Diff(sourceRoot string, source map[string][]FileInfo, destinationRoot string, destination map[string][]FileInfo) DiffResult {
+	result := DiffResult{
+		OnlyInSource:      []FileInfo{},
+		OnlyInDestination: []FileInfo{},
+		Relocated:         []RelocatedFile{},
+	}
+
+	for hash, sourceGroup := range source {
+		destinationGroup, exists := destination[hash]
+		if !exists {
+			result.OnlyInSource = append(result.OnlyInSource, sourceGroup...)
+			continue
+		}
+		result.Relocated = append(result.Relocated,
+			findRelocated(sourceRoot, sourceGroup, destinationRoot, destinationGroup)...)
+	}
+	for hash, destinationGroup := range destination {
+		if _, exists := source[hash]; !exists {
+			result.OnlyInDestination = append(result.OnlyInDestination, destinationGroup...)
+		}
+	}
+
+	sortFilesByPath(result.OnlyInSource)
+	sortFilesByPath(result.OnlyInDestination)
+	sort.Slice(result.Relocated, func(i, j int) bool {
+		return result.Relocated[i].Source.Path < result.Relocated[j].Source.Path
+	})
+	return result
+}
+
+// findRelocated returns the files of sourceGroup that have no counterpart at
+// the same relative path in destinationGroup.
+func findRelocated(sourceRoot string, sourceGroup []FileInfo, destinationRoot string, destinationGroup []FileInfo) []RelocatedFile {
+	destinationPaths := make(map[string]bool, len(destinationGroup))
+	for _, fileInfo := range destinationGroup {
+		destinationPaths[relativePath(destinationRoot, fileInfo.Path)] = true
+	}
+
+	var relocated []RelocatedFile
+	for _, fileInfo := range sourceGroup {
+		if destinationPaths[relativePath(sourceRoot, fileInfo.Path)] {
+			continue
+		}
+		destinations := append([]FileInfo(nil), destinationGroup...)
+		sortFilesByPath(destinations)
+		relocated = append(relocated, RelocatedFile{Source: fileInfo, Destinations: destinations})
+	}
+	return relocated
+}
+
+// relativePath returns path relative to root, or path itself if it does not
+// lie below root.
+func relativePath(root, path string) string {
+	relative, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
+	}
+	return relative
+}
+
+// sortFilesByPath sorts files in place by their path.
+func sortFilesByPath(files []FileInfo) {
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+}
@@ -0,0 +1,93 @@
+package finder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name              string
+		source            map[string]string
+		destination       map[string]string
+		onlyInSource      []string
+		onlyInDestination []string
+		relocated         map[string][]string
+	}{
+		{
+			name:              "identical trees",
+			source:            map[string]string{"a": "1", "dir/b": "2"},
+			destination:       map[string]string{"a": "1", "dir/b": "2"},
+			onlyInSource:      []string{},
+			onlyInDestination: []string{},
+			relocated:         map[string][]string{},
+		},
+		{
+			name:              "added and removed files",
+			source:            map[string]string{"common": "1", "removed": "2"},
+			destination:       map[string]string{"common": "1", "added": "3"},
+			onlyInSource:      []string{"removed"},
+			onlyInDestination: []string{"added"},
+			relocated:         map[string][]string{},
+		},
+		{
+			name:              "changed content",
+			source:            map[string]string{"file": "old"},
+			destination:       map[string]string{"file": "new"},
+			onlyInSource:      []string{"file"},
+			onlyInDestination: []string{"file"},
+			relocated:         map[string][]string{},
+		},
+		{
+			name:              "moved file",
+			source:            map[string]string{"old/name": "1"},
+			destination:       map[string]string{"new/name": "1"},
+			onlyInSource:      []string{},
+			onlyInDestination: []string{},
+			relocated:         map[string][]string{"old/name": {"new/name"}},
+		},
+		{
+			name:              "copy kept in place",
+			source:            map[string]string{"a": "1"},
+			destination:       map[string]string{"a": "1", "b": "1"},
+			onlyInSource:      []string{},
+			onlyInDestination: []string{},
+			relocated:         map[string][]string{},
+		},
+		{
+			name:              "duplicates merged",
+			source:            map[string]string{"a": "1", "b": "1"},
+			destination:       map[string]string{"a": "1"},
+			onlyInSource:      []string{},
+			onlyInDestination: []string{},
+			relocated:         map[string][]string{"b": {"a"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sourceRoot, destinationRoot := t.TempDir(), t.TempDir()
+			writeTree(t, sourceRoot, test.source)
+			writeTree(t, destinationRoot, test.destination)
+
+			result := Diff(
+				sourceRoot, find(t, NewDefaultFinder(sourceRoot)),
+				destinationRoot, find(t, NewDefaultFinder(destinationRoot)),
+			)
+
+			if got := relativePaths(t, sourceRoot, result.OnlyInSource); !reflect.DeepEqual(got, test.onlyInSource) {
+				t.Errorf("OnlyInSource = %v, want %v", got, test.onlyInSource)
+			}
+			if got := relativePaths(t, destinationRoot, result.OnlyInDestination); !reflect.DeepEqual(got, test.onlyInDestination) {
+				t.Errorf("OnlyInDestination = %v, want %v", got, test.onlyInDestination)
+			}
+			relocated := map[string][]string{}
+			for _, file := range result.Relocated {
+				source := relativePaths(t, sourceRoot, []FileInfo{file.Source})[0]
+				relocated[source] = relativePaths(t, destinationRoot, file.Destinations)
+			}
+			if !reflect.DeepEqual(relocated, test.relocated) {
+				t.Errorf("Relocated = %v, want %v", relocated, test.relocated)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package finder
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirectoryInfo holds metadata about a directory including its Merkle hash.
+//
+// The hash is derived from the hashes of all files and subdirectories below
+// the directory, so two directories share a hash when their trees match.
+type DirectoryInfo struct {
+	// Path is the absolute path to the directory.
+	Path string `json:"path"`
+	// Size is the total size in bytes of all files below the directory.
+	Size int64 `json:"size"`
+	// FileCount is the number of files below the directory.
+	FileCount int `json:"fileCount"`
+	// Hash is the hexadecimal-encoded Merkle hash of the directory tree.
+	Hash string `json:"hash"`
+}
+
+// DirectoryResult holds duplicate directory trees alongside the file groups
+// they do not explain.
+type DirectoryResult struct {
+	// Directories maps directory hashes to the duplicate trees sharing them.
+	Directories map[string][]DirectoryInfo `json:"directories"`
+	// Files maps file hashes to file groups not covered by Directories.
+	Files map[string][]FileInfo `json:"files"`
+}
+
+// directoryNode is a directory in the tree reconstructed from a Find result.
+type directoryNode struct {
+	path           string
+	files          []directoryFile
+	subdirectories []*directoryNode
+}
+
+// directoryFile is a file entry of a directoryNode along with its group key.
+type directoryFile struct {
+	key  string
+	info FileInfo
+}
+
+// GroupDirectories detects duplicate directory trees in a Find result.
+//
+// Each directory is assigned a Merkle-style hash computed from the names and
+// hashes of its children. When contentOnly is true, names are left out of the
+// hash so directories match on content alone. Only the outermost duplicate
+// trees are reported, and file groups whose members all lie inside a reported
+// directory are collapsed into it.
+//
+// Directories are hashed from the files in the result only, so files left
+// out by a filter, such as the file type of a finder or a size limit, do not
+// tell directories apart, and empty subdirectories are ignored. Archive
+// members are left out too, so archives count as files rather than as
+// directories. Directories above root, the scanned directory, are not hashed.
+func GroupDirectories(files map[string][]FileInfo, root string, contentOnly bool) DirectoryResult {
+	roots := buildDirectoryTree(files, filepath.Clean(root))
+
+	byHash := make(map[string][]DirectoryInfo)
+	for _, root := range roots {
+		hashDirectory(root, contentOnly, byHash)
+	}
+
+	directories := collapseDirectoryGroups(byHash)
+	return DirectoryResult{
+		Directories: directories,
+		Files:       collapseFileGroups(files, directories),
+	}
+}
+
+// buildDirectoryTree links every file in files to its parent directory and
+// returns the topmost directories of the resulting forest. The tree stops at
+// root; files outside root are linked up to the filesystem root. Virtual
+// files are skipped, as the archives holding them are not directories.
+func buildDirectoryTree(files map[string][]FileInfo, root string) []*directoryNode {
+	nodes := make(map[string]*directoryNode)
+	var roots []*directoryNode
+
+	var nodeFor func(path string) *directoryNode
+	nodeFor = func(path string) *directoryNode {
+		if node, ok := nodes[path]; ok {
+			return node
+		}
+		node := &directoryNode{path: path}
+		nodes[path] = node
+		parent := filepath.Dir(path)
+		if parent == path || path == root {
+			roots = append(roots, node)
+		} else {
+			parentNode := nodeFor(parent)
+			parentNode.subdirectories = append(parentNode.subdirectories, node)
+		}
+		return node
+	}
+
+	for key, group := range files {
+		for _, fileInfo := range group {
+			if fileInfo.Virtual {
+				continue
+			}
+			node := nodeFor(filepath.Dir(fileInfo.Path))
+			node.files = append(node.files, directoryFile{key, fileInfo})
+		}
+	}
+	return roots
+}
+
+// hashDirectory computes the Merkle hash of node and its subdirectories,
+// recording every directory in byHash.
+func hashDirectory(node *directoryNode, contentOnly bool, byHash map[string][]DirectoryInfo) *DirectoryInfo {
+	info := &DirectoryInfo{Path: node.path}
+	entries := make([]string, 0, len(node.files)+len(node.subdirectories))
+
+	for _, file := range node.files {
+		info.Size += file.info.Size
+		info.FileCount++
+		entries = append(entries, directoryEntry("f", file.info.Name, file.key, contentOnly))
+	}
+	for _, subdirectory := range node.subdirectories {
+		child := hashDirectory(subdirectory, contentOnly, byHash)
+		info.Size += child.Size
+		info.FileCount += child.FileCount
+		entries = append(entries, directoryEntry("d", filepath.Base(subdirectory.path), child.Hash, contentOnly))
+	}
+
+	sort.Strings(entries)
+	info.Hash = fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(entries, "\n"))))
+	byHash[info.Hash] = append(byHash[info.Hash], *info)
+	return info
+}
+
+// directoryEntry encodes a single child of a directory for hashing.
+func directoryEntry(kind, name, hash string, contentOnly bool) string {
+	if contentOnly {
+		return kind + "\x00" + hash
+	}
+	return kind + "\x00" + name + "\x00" + hash
+}
+
+// collapseDirectoryGroups keeps the groups of byHash with more than one
+// member, dropping groups whose members are all nested in other duplicates.
+func collapseDirectoryGroups(byHash map[string][]DirectoryInfo) map[string][]DirectoryInfo {
+	duplicated := make(map[string]bool)
+	for _, group := range byHash {
+		if len(group) < 2 {
+			continue
+		}
+		for _, directory := range group {
+			duplicated[directory.Path] = true
+		}
+	}
+
+	directories := make(map[string][]DirectoryInfo)
+	for hash, group := range byHash {
+		if len(group) < 2 || allNestedIn(group, duplicated) {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].Path < group[j].Path })
+		directories[hash] = group
+	}
+	return directories
+}
+
+// allNestedIn reports whether every directory in group has an ancestor in set.
+func allNestedIn(group []DirectoryInfo, set map[string]bool) bool {
+	for _, directory := range group {
+		if !hasAncestorIn(directory.Path, set) {
+			return false
+		}
+	}
+	return true
+}
+
+// collapseFileGroups returns the file groups of files that are not fully
+// explained by the reported duplicate directories.
+func collapseFileGroups(files map[string][]FileInfo, directories map[string][]DirectoryInfo) map[string][]FileInfo {
+	reported := make(map[string]bool)
+	for _, group := range directories {
+		for _, directory := range group {
+			reported[directory.Path] = true
+		}
+	}
+
+	remaining := make(map[string][]FileInfo)
+	for key, group := range files {
+		explained := true
+		for _, fileInfo := range group {
+			if !hasAncestorIn(fileInfo.Path, reported) {
+				explained = false
+				break
+			}
+		}
+		if !explained {
+			remaining[key] = group
+		}
+	}
+	return remaining
+}
+
+// hasAncestorIn reports whether any strict ancestor of path is in set.
+func hasAncestorIn(path string, set map[string]bool) bool {
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return false
+		}
+		if set[parent] {
+			return true
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,138 @@
+package finder
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// testFile describes a file of a Find result.
+type testFile struct {
+	path    string
+	hash    string
+	virtual bool
+}
+
+// findResult groups files by hash as Find does.
+func findResult(files []testFile) map[string][]FileInfo {
+	result := make(map[string][]FileInfo)
+	for _, file := range files {
+		result[file.hash] = append(result[file.hash], FileInfo{
+			Name:    filepath.Base(file.path),
+			Path:    file.path,
+			Size:    1,
+			Hash:    file.hash,
+			Virtual: file.virtual,
+		})
+	}
+	return result
+}
+
+func TestGroupDirectories(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       []testFile
+		contentOnly bool
+		directories [][]string
+		remaining   []string
+	}{
+		{
+			name: "nested identical trees",
+			files: []testFile{
+				{path: "/r/a/x", hash: "1"}, {path: "/r/a/sub/y", hash: "2"},
+				{path: "/r/b/x", hash: "1"}, {path: "/r/b/sub/y", hash: "2"},
+			},
+			directories: [][]string{{"/r/a", "/r/b"}},
+		},
+		{
+			name: "three copies and a loose file",
+			files: []testFile{
+				{path: "/r/a/x", hash: "1"}, {path: "/r/b/x", hash: "1"}, {path: "/r/c/x", hash: "1"},
+				{path: "/r/x", hash: "1"},
+			},
+			directories: [][]string{{"/r/a", "/r/b", "/r/c"}},
+			remaining:   []string{"1"},
+		},
+		{
+			name: "partial overlap",
+			files: []testFile{
+				{path: "/r/a/x", hash: "1"}, {path: "/r/a/y", hash: "2"},
+				{path: "/r/b/x", hash: "1"},
+			},
+			remaining: []string{"1", "2"},
+		},
+		{
+			name:      "renamed file",
+			files:     []testFile{{path: "/r/a/x", hash: "1"}, {path: "/r/b/renamed", hash: "1"}},
+			remaining: []string{"1"},
+		},
+		{
+			name:        "renamed file with content only",
+			files:       []testFile{{path: "/r/a/x", hash: "1"}, {path: "/r/b/renamed", hash: "1"}},
+			contentOnly: true,
+			directories: [][]string{{"/r/a", "/r/b"}},
+		},
+		{
+			name:        "renamed subdirectory",
+			files:       []testFile{{path: "/r/a/sub/x", hash: "1"}, {path: "/r/b/other/x", hash: "1"}},
+			directories: [][]string{{"/r/a/sub", "/r/b/other"}},
+		},
+		{
+			name:        "renamed subdirectory with content only",
+			files:       []testFile{{path: "/r/a/sub/x", hash: "1"}, {path: "/r/b/other/x", hash: "1"}},
+			contentOnly: true,
+			directories: [][]string{{"/r/a", "/r/b"}},
+		},
+		{
+			name: "archive members",
+			files: []testFile{
+				{path: "/r/a/z.zip", hash: "9"},
+				{path: "/r/a/z.zip!/m", hash: "1", virtual: true},
+				{path: "/r/c/m", hash: "1"},
+			},
+			remaining: []string{"1", "9"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := GroupDirectories(findResult(test.files), "/r", test.contentOnly)
+
+			var directories [][]string
+			for _, group := range result.Directories {
+				var paths []string
+				for _, directory := range group {
+					paths = append(paths, directory.Path)
+				}
+				directories = append(directories, paths)
+			}
+			sort.Slice(directories, func(i, j int) bool { return directories[i][0] < directories[j][0] })
+			if !reflect.DeepEqual(directories, test.directories) {
+				t.Errorf("GroupDirectories() directories = %v, want %v", directories, test.directories)
+			}
+
+			var remaining []string
+			for key := range result.Files {
+				remaining = append(remaining, key)
+			}
+			sort.Strings(remaining)
+			if !reflect.DeepEqual(remaining, test.remaining) {
+				t.Errorf("GroupDirectories() files = %v, want %v", remaining, test.remaining)
+			}
+		})
+	}
+}
+
+func TestGroupDirectoriesTotals(t *testing.T) {
+	files := findResult([]testFile{
+		{path: "/r/a/x", hash: "1"}, {path: "/r/a/sub/y", hash: "2"},
+		{path: "/r/b/x", hash: "1"}, {path: "/r/b/sub/y", hash: "2"},
+	})
+	for _, group := range GroupDirectories(files, "/r", false).Directories {
+		for _, directory := range group {
+			if directory.FileCount != 2 || directory.Size != 2 {
+				t.Errorf("%s has %d files of %d bytes, want 2 files of 2 bytes", directory.Path, directory.FileCount, directory.Size)
+			}
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package finder
+
+import (
+	"os"
+)
+
+// fileIdentity returns the device and inode numbers of the file described
+// by info. They are not available on this platform.
+func fileIdentity(os.FileInfo) (device uint64, inode uint64, ok bool) {
+	return 0, 0, false
+}
+
+// fileOwner returns the user and group IDs owning the file described by
+// info. They are not available on this platform.
+func fileOwner(os.FileInfo) (uid uint32, gid uint32, ok bool) {
+	return 0, 0, false
+}
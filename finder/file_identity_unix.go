@@ -0,0 +1,28 @@
+//go:build unix
+
+package finder
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the device and inode numbers of the file described
+// by info. The boolean result is false if they are unavailable.
+func fileIdentity(info os.FileInfo) (device uint64, inode uint64, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return uint64(stat.Dev), uint64(stat.Ino), true
+}
+
+// fileOwner returns the user and group IDs owning the file described by
+// info. The boolean result is false if they are unavailable.
+func fileOwner(info os.FileInfo) (uid uint32, gid uint32, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return stat.Uid, stat.Gid, true
+}
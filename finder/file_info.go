@@ -1,5 +1,9 @@
 package finder
 
+import (
+	"os"
+)
+
 // FileInfo holds metadata about a file including its computed content hash.
 //
 // The struct is JSON-serializable for output formatting.
@@ -12,4 +16,49 @@ type FileInfo struct {
 	Size int64 `json:"size"`
 	// Hash is the hexadecimal-encoded content hash.
 	Hash string `json:"hash"`
+	// Algorithm is the hash algorithm that produced Hash.
+	Algorithm string `json:"algorithm"`
+	// Hasher is the name of the hasher that extracted the hashed content.
+	Hasher string `json:"hasher"`
+	// Device is the ID of the device containing the file, if known.
+	Device uint64 `json:"device,omitempty"`
+	// Inode is the inode number of the file, if known.
+	// Hardlinks to the same file share Device and Inode.
+	Inode uint64 `json:"inode,omitempty"`
+	// Mode holds the POSIX permission bits of the file along with the
+	// setuid, setgid and sticky bits, as in the lower 12 bits of st_mode,
+	// so 0644 for a file readable by all and writable by its owner.
+	Mode uint32 `json:"mode"`
+	// UID is the user ID of the file's owner, or nil if unknown.
+	UID *uint32 `json:"uid,omitempty"`
+	// GID is the group ID of the file's group, or nil if unknown.
+	GID *uint32 `json:"gid,omitempty"`
+	// Virtual reports whether the file is a member of an archive rather than
+	// a file on disk. Virtual files can be reported, but actions such as
+	// deleting or linking duplicates must refuse to act on them.
+	Virtual bool `json:"virtual,omitempty"`
+}
+
+// posixMode returns the POSIX permission, setuid, setgid and sticky bits of
+// mode, which os.FileMode keeps apart from the permission bits.
+func posixMode(mode os.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 0o4000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 0o2000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 0o1000
+	}
+	return bits
+}
+
+// GroupKey returns the key under which the file is grouped with its duplicates.
+//
+// The key qualifies Hash with Hasher and Algorithm, so hashes computed by
+// different hashers or algorithms never fall into the same group.
+func (f FileInfo) GroupKey() string {
+	return f.Hasher + "/" + f.Algorithm + ":" + f.Hash
 }
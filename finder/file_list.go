@@ -0,0 +1,106 @@
+package finder
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fdups/log"
+
+	"go.uber.org/zap"
+)
+
+// WithFileList makes a Finder hash the files listed in r instead of walking
+// the target directory.
+//
+// Entries are separated by separator, typically '\n' or '\x00'. Relative
+// entries are resolved against the target directory. Entries that cannot be
+// stat'ed are logged and skipped; directories are ignored. A file listed
+// more than once, for example by a relative and an absolute path, is
+// processed once.
+func WithFileList(r io.Reader, separator byte) Option {
+	return func(f *baseFinder) {
+		f.walker = func() chan walkDirectoryYield {
+			return f.readFileList(r, separator)
+		}
+	}
+}
+
+func (f *baseFinder) readFileList(r io.Reader, separator byte) chan walkDirectoryYield {
+	log.L().Debug("Starting reading file list")
+	channel := make(chan walkDirectoryYield)
+
+	go func() {
+		defer close(channel)
+		reader := bufio.NewReader(r)
+		listed := make(map[string]bool)
+		for {
+			entry, err := reader.ReadString(separator)
+			if f.processListEntry(trimListEntry(entry, separator), listed, channel) != nil {
+				return
+			}
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				_ = f.yield(walkDirectoryYield{err: err}, channel)
+				return
+			}
+		}
+	}()
+
+	return channel
+}
+
+// trimListEntry strips the separator, and a carriage return preceding a
+// newline separator, from entry.
+func trimListEntry(entry string, separator byte) string {
+	entry = strings.TrimSuffix(entry, string(separator))
+	if separator == '\n' {
+		entry = strings.TrimSuffix(entry, "\r")
+	}
+	return entry
+}
+
+// processListEntry yields the file at path unless it is in listed, the set
+// of cleaned paths listed before, which it is added to. Returns
+// errWalkStopped if Find returned in the meantime.
+func (f *baseFinder) processListEntry(path string, listed map[string]bool, channel chan<- walkDirectoryYield) error {
+	if path == "" {
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(f.targetDirectory, path)
+	}
+	path = filepath.Clean(path)
+	if listed[path] {
+		log.L().Debug("Skipped listed file (listed before)", zap.String("path", path))
+		return nil
+	}
+	listed[path] = true
+
+	info, err := os.Stat(path)
+	if err != nil {
+		log.L().Warn("Skipped listed file (stat failed)", zap.String("path", path), zap.Error(err))
+		return nil
+	}
+	if info.IsDir() {
+		log.L().Debug("Skipped listed directory", zap.String("path", path))
+		return nil
+	}
+	if !info.Mode().IsRegular() {
+		f.recordSpecialFile(path, info)
+		return nil
+	}
+	if !f.fileFilter(path, info) {
+		log.L().Debug("Skipped file (filtered)", zap.String("name", info.Name()))
+	} else {
+		log.L().Debug("Listed file", zap.String("name", info.Name()))
+		if err := f.yield(walkDirectoryYield{fileInfo: newFileInfo(path, info)}, channel); err != nil {
+			return err
+		}
+	}
+	return f.walkArchive(path, channel)
+}
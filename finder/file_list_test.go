@@ -0,0 +1,86 @@
+package finder
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithFileList(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		list      string
+		separator byte
+		found     []string
+	}{
+		{
+			name:      "newline separated",
+			files:     map[string]string{"a": "1", "dir/b": "2", "c": "3"},
+			list:      "a\ndir/b\n",
+			separator: '\n',
+			found:     []string{"a", "dir/b"},
+		},
+		{
+			name:      "carriage returns",
+			files:     map[string]string{"a": "1", "b": "2"},
+			list:      "a\r\nb\r\n",
+			separator: '\n',
+			found:     []string{"a", "b"},
+		},
+		{
+			name:      "file listed twice",
+			files:     map[string]string{"a": "1", "b": "2"},
+			list:      "a\nb\n./a\n{root}/a\ndir/../a",
+			separator: '\n',
+			found:     []string{"a", "b"},
+		},
+		{
+			name:      "NUL separated with newline in name",
+			files:     map[string]string{"new\nline": "1", "plain": "2"},
+			list:      "new\nline\x00plain\x00",
+			separator: '\x00',
+			found:     []string{"new\nline", "plain"},
+		},
+		{
+			name:      "missing files, directories and empty entries",
+			files:     map[string]string{"a": "1", "dir/b": "2"},
+			list:      "\nmissing\ndir\n\na\n",
+			separator: '\n',
+			found:     []string{"a"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, test.files)
+			list := strings.ReplaceAll(test.list, "{root}", root)
+
+			result := find(t, NewDefaultFinder(root, WithFileList(strings.NewReader(list), test.separator)))
+			var files []FileInfo
+			for _, group := range result {
+				files = append(files, group...)
+			}
+			if found := relativePaths(t, root, files); !reflect.DeepEqual(found, test.found) {
+				t.Errorf("Find() hashed %q, want %q", found, test.found)
+			}
+		})
+	}
+}
+
+func TestWithFileListSkipsRepeatedAbsolutePaths(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"a": "same", "b": "same"})
+	list := strings.Join([]string{filepath.Join(root, "a"), filepath.Join(root, "b"), filepath.Join(root, "a")}, "\n")
+
+	result := find(t, NewDefaultFinder(root, WithFileList(strings.NewReader(list), '\n')))
+	if groups := len(result); groups != 1 {
+		t.Fatalf("Find() returned %d groups, want 1", groups)
+	}
+	for _, group := range result {
+		if paths := relativePaths(t, root, group); !reflect.DeepEqual(paths, []string{"a", "b"}) {
+			t.Errorf("Find() grouped %q, want [a b]", paths)
+		}
+	}
+}
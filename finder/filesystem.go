@@ -0,0 +1,96 @@
+package finder
+
+import (
+	"os"
+	"strings"
+
+	"fdups/log"
+
+	"go.uber.org/zap"
+)
+
+// mountedFilesystem is a mounted filesystem identified by its device number.
+type mountedFilesystem struct {
+	device uint64
+	fsType string
+}
+
+// WithOneFileSystem keeps a Finder on the filesystem of the target
+// directory, like du -x: directories on a different device than the target
+// directory, such as mount points of other filesystems, are not walked.
+func WithOneFileSystem() Option {
+	return func(f *baseFinder) {
+		f.oneFileSystem = true
+	}
+}
+
+// WithExcludedFilesystemTypes makes a Finder skip directories on
+// filesystems of the given types, as listed in /proc/self/mountinfo.
+//
+// A type also excludes its subtypes, so "fuse" excludes "fuse.sshfs".
+// Filesystem types are only available on Linux; elsewhere Find fails.
+func WithExcludedFilesystemTypes(types ...string) Option {
+	return func(f *baseFinder) {
+		f.excludedFsTypes = append(f.excludedFsTypes, types...)
+	}
+}
+
+// loadExcludedDevices looks up the devices of the mounted filesystems
+// whose type is excluded.
+func (f *baseFinder) loadExcludedDevices() error {
+	if len(f.excludedFsTypes) == 0 {
+		return nil
+	}
+	filesystems, err := mountedFilesystems()
+	if err != nil {
+		return err
+	}
+
+	f.excludedDevices = make(map[uint64]string)
+	for _, filesystem := range filesystems {
+		if fsTypeExcluded(filesystem.fsType, f.excludedFsTypes) {
+			f.excludedDevices[filesystem.device] = filesystem.fsType
+		}
+	}
+	log.L().Debug("Excluded filesystems loaded", zap.Int("count", len(f.excludedDevices)))
+	return nil
+}
+
+// fsTypeExcluded reports whether fsType is one of excluded or a subtype of one.
+func fsTypeExcluded(fsType string, excluded []string) bool {
+	for _, name := range excluded {
+		if fsType == name || strings.HasPrefix(fsType, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// onWalkedFilesystem reports whether the directory described by info lies
+// on a filesystem the finder walks. The first directory checked is the
+// target directory, whose device the others are compared with.
+func (f *baseFinder) onWalkedFilesystem(path string, info os.FileInfo) bool {
+	if !f.oneFileSystem && f.excludedDevices == nil {
+		return true
+	}
+	device, _, ok := fileIdentity(info)
+	if !ok {
+		return true
+	}
+
+	if fsType, excluded := f.excludedDevices[device]; excluded {
+		log.L().Info("Skipped directory (excluded filesystem)", zap.String("path", path), zap.String("fstype", fsType))
+		return false
+	}
+	if !f.oneFileSystem {
+		return true
+	}
+	if !f.rootDeviceKnown {
+		f.rootDevice, f.rootDeviceKnown = device, true
+	}
+	if device != f.rootDevice {
+		log.L().Info("Skipped directory (other filesystem)", zap.String("path", path))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,16 @@
+package finder
+
+import (
+	"os"
+)
+
+// SizeFilter returns a FileFilter that accepts files whose size lies within
+// [minSize, maxSize]. A maxSize of zero or less leaves the upper bound open.
+func SizeFilter(minSize, maxSize int64) FileFilter {
+	return func(_ string, info os.FileInfo) bool {
+		if info.Size() < minSize {
+			return false
+		}
+		return maxSize <= 0 || info.Size() <= maxSize
+	}
+}
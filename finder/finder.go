@@ -2,10 +2,52 @@
 // in a directory tree by computing and comparing content hashes.
 //
 // The package uses a concurrent worker pool to process files in parallel,
-// making it efficient for large directory trees. Different finder
+// and reads directories ahead of the walk with a bounded number of
+// goroutines, making it efficient for large directory trees and network
+// filesystems. Files are still walked in lexical order. Different finder
 // implementations support various file types and hashing strategies:
-//   - DefaultFinder: processes all files using SHA-256 hash
+//   - DefaultFinder: processes all files by hashing their raw content
 //   - FlacFinder: processes only FLAC files, hashing decoded audio content
+//   - AudioFinder: processes FLAC, WAV and AIFF files, hashing decoded audio
+//     content regardless of container format
+//   - Mp3Finder: processes only MP3 files, hashing audio frames without tags
+//   - ImageFinder: processes JPEG and PNG files, hashing image data without
+//     metadata
+//   - Mp4Finder: processes MP4 and QuickTime files, hashing media data
+//     without metadata
+//   - OfficeFinder: processes zip-based office documents, hashing their
+//     unpacked parts
+//   - StructuredFinder: processes JSON and YAML files, hashing their data
+//     regardless of key order and formatting
+//   - CompositeFinder: processes all files, routing each to a hasher by
+//     extension or sniffed MIME type
+//   - TextFinder: processes all files, hashing detected text files with
+//     line endings and trailing whitespace normalized
+//   - SimilarImagesFinder: processes JPEG, PNG and GIF files, grouping
+//     visually similar images by perceptual hash
+//   - SimilarTextFinder: processes text files, grouping documents with
+//     mostly the same wording by MinHash signature
+//
+// Finders that detect near-duplicates implement SimilarityFinder, whose
+// FindSimilar method returns SimilarityGroup values scoring each member
+// instead of groups of identical hashes.
+//
+// With WithArchives, finders also process the members of zip and tar
+// archives as virtual files, which are reported but never acted upon.
+// Symbolic links are skipped by default; WithSymlinks reports them or
+// follows them with protection against cycles. WithOneFileSystem and
+// WithExcludedFilesystemTypes keep the walk off other filesystems. Only
+// regular files are hashed: special files such as FIFOs and device nodes,
+// and paths that cannot be read for lack of permission, are listed in the
+// Diagnostics instead.
+//
+// ChunkAnalyzer reuses the directory walking and worker pool of the finders
+// to split files into content-defined chunks, estimating how much
+// block-level deduplication would save.
+//
+// Finders are also available by name through a registry. Register adds a
+// finder built from a file filter and a hasher, and New creates a
+// registered finder, so new finders can be added without changing callers.
 package finder
 
 // Finder defines the interface for duplicate file detection.
@@ -14,8 +56,8 @@ package finder
 // and group files by their hash values.
 type Finder interface {
 	// Find scans the target directory and returns files grouped by hash.
-	// The returned map uses hash strings as keys, with slices of FileInfo
-	// for all files sharing that hash. Files appearing alone in a group
+	// The returned map uses FileInfo.GroupKey values as keys, with slices of
+	// FileInfo for all files sharing that key. Files appearing alone in a group
 	// have no duplicates.
 	//
 	// Returns an error if directory traversal or file processing fails.
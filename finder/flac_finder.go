@@ -8,6 +8,14 @@ import (
 	"fdups/hasher"
 )
 
+func init() {
+	Register(Registration{
+		Name:        "flac",
+		Description: "FLAC files, hashed by decoded audio",
+		New:         NewFlacFinder,
+	})
+}
+
 // flacFinder finds duplicate FLAC audio files by comparing decoded audio content.
 // It only processes files with the .flac extension and computes hashes based on
 // the raw PCM samples, ignoring metadata differences.
@@ -22,12 +30,13 @@ type flacFinder struct {
 // rather than raw file bytes. This means two FLAC files with identical
 // audio but different metadata or encoding parameters will be detected
 // as duplicates.
-func NewFlacFinder(targetDirectory string) Finder {
+func NewFlacFinder(targetDirectory string, options ...Option) Finder {
 	return &flacFinder{
 		baseFinder: newBaseFinder(
 			targetDirectory,
-			hasher.NewFlacHasher(),
+			hasher.NewFlacHasher,
 			acceptFlacFiles,
+			options,
 		),
 	}
 }
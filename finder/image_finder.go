@@ -0,0 +1,58 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fdups/hasher"
+)
+
+// imageExtensions lists the extensions of the image files processed by the
+// image finder.
+var imageExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".jpe":  true,
+	".png":  true,
+}
+
+func init() {
+	Register(Registration{
+		Name:        "image",
+		Description: "JPEG and PNG files, hashed by image data without metadata",
+		New:         NewImageFinder,
+	})
+}
+
+// imageFinder finds duplicate JPEG and PNG images by comparing their image
+// data, ignoring EXIF, XMP, ICC and other metadata blocks.
+type imageFinder struct {
+	*baseFinder
+}
+
+// NewImageFinder creates a Finder that processes only JPEG and PNG files in
+// the target directory.
+//
+// EXIF, XMP and ICC segments of JPEG files and ancillary chunks of PNG files
+// other than the tRNS transparency are skipped, so two copies of an image
+// that differ only in metadata added by editing or cataloguing tools are
+// detected as duplicates. The image data
+// is not decoded: a recompressed or converted copy is a different image to
+// this finder, unlike to NewSimilarImagesFinder.
+func NewImageFinder(targetDirectory string, options ...Option) Finder {
+	return &imageFinder{
+		baseFinder: newBaseFinder(
+			targetDirectory,
+			hasher.NewImageHasher,
+			acceptImageFiles,
+			options,
+		),
+	}
+}
+
+// acceptImageFiles is a FileFilter that accepts only JPEG and PNG files by
+// extension (case-insensitive).
+func acceptImageFiles(path string, _ os.FileInfo) bool {
+	return imageExtensions[strings.ToLower(filepath.Ext(path))]
+}
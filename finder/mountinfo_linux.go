@@ -0,0 +1,66 @@
+//go:build linux
+
+package finder
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// mountInfoPath is the kernel's description of the mounts visible to the
+// current process.
+const mountInfoPath = "/proc/self/mountinfo"
+
+// mountedFilesystems returns the filesystems mounted on the system, read
+// from /proc/self/mountinfo.
+func mountedFilesystems() ([]mountedFilesystem, error) {
+	file, err := os.Open(mountInfoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var filesystems []mountedFilesystem
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		filesystem, err := parseMountInfoLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", mountInfoPath, err)
+		}
+		filesystems = append(filesystems, filesystem)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return filesystems, nil
+}
+
+// parseMountInfoLine parses a line of /proc/self/mountinfo of the form
+//
+//	36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+//
+// where the third field is the device number and the field following the
+// "-" separator is the filesystem type.
+func parseMountInfoLine(line string) (mountedFilesystem, error) {
+	fields := strings.Fields(line)
+	separator := -1
+	for i := 6; i < len(fields); i++ {
+		if fields[i] == "-" {
+			separator = i
+			break
+		}
+	}
+	if separator < 0 || separator+1 >= len(fields) {
+		return mountedFilesystem{}, fmt.Errorf("malformed line %q", line)
+	}
+
+	var major, minor uint32
+	if _, err := fmt.Sscanf(fields[2], "%d:%d", &major, &minor); err != nil {
+		return mountedFilesystem{}, fmt.Errorf("malformed device number in line %q", line)
+	}
+	return mountedFilesystem{device: unix.Mkdev(major, minor), fsType: fields[separator+1]}, nil
+}
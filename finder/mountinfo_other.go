@@ -0,0 +1,13 @@
+//go:build !linux
+
+package finder
+
+import (
+	"errors"
+)
+
+// mountedFilesystems returns the filesystems mounted on the system. They
+// are not available on this platform.
+func mountedFilesystems() ([]mountedFilesystem, error) {
+	return nil, errors.New("filesystem types are only available on Linux")
+}
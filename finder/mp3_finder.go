@@ -0,0 +1,49 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fdups/hasher"
+)
+
+func init() {
+	Register(Registration{
+		Name:        "mp3",
+		Description: "MP3 files, hashed by audio frames without tags",
+		New:         NewMp3Finder,
+	})
+}
+
+// mp3Finder finds duplicate MP3 files by comparing their MPEG audio frames.
+// It only processes files with the .mp3 extension and ignores ID3, APE and
+// encoder info tags.
+type mp3Finder struct {
+	*baseFinder
+}
+
+// NewMp3Finder creates a Finder that processes only MP3 files in the target
+// directory.
+//
+// The MPEG audio frames are hashed as stored, without decoding, while ID3
+// and APE tags and the encoder's Xing, Info or VBRI frame are skipped. Two
+// copies of an MP3 that differ only in their tags are therefore detected as
+// duplicates, while re-encoded copies are not. Files without audio frames
+// are listed as unparseable in the Diagnostics.
+func NewMp3Finder(targetDirectory string, options ...Option) Finder {
+	return &mp3Finder{
+		baseFinder: newBaseFinder(
+			targetDirectory,
+			hasher.NewMp3Hasher,
+			acceptMp3Files,
+			options,
+		),
+	}
+}
+
+// acceptMp3Files is a FileFilter that accepts only .mp3 files (case-insensitive).
+func acceptMp3Files(path string, _ os.FileInfo) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".mp3"
+}
@@ -0,0 +1,58 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fdups/hasher"
+)
+
+// mp4Extensions lists the extensions of the ISO base media files processed
+// by the mp4 finder.
+var mp4Extensions = map[string]bool{
+	".mp4": true,
+	".m4v": true,
+	".m4a": true,
+	".mov": true,
+	".3gp": true,
+	".3g2": true,
+}
+
+func init() {
+	Register(Registration{
+		Name:        "mp4",
+		Description: "MP4 and QuickTime files, hashed by media data without metadata",
+		New:         NewMp4Finder,
+	})
+}
+
+// mp4Finder finds duplicate MP4 and QuickTime files by comparing their media
+// data, ignoring the moov box and tags.
+type mp4Finder struct {
+	*baseFinder
+}
+
+// NewMp4Finder creates a Finder that processes only MP4, QuickTime and
+// related files in the target directory.
+//
+// Only the encoded samples in mdat boxes are hashed, so a video that was
+// re-tagged, or re-muxed without re-encoding into a container with a
+// different moov box, is detected as a duplicate. Other ISO base media
+// files, such as HEIC images, are not processed.
+func NewMp4Finder(targetDirectory string, options ...Option) Finder {
+	return &mp4Finder{
+		baseFinder: newBaseFinder(
+			targetDirectory,
+			hasher.NewMp4Hasher,
+			acceptMp4Files,
+			options,
+		),
+	}
+}
+
+// acceptMp4Files is a FileFilter that accepts only ISO base media files by
+// extension (case-insensitive).
+func acceptMp4Files(path string, _ os.FileInfo) bool {
+	return mp4Extensions[strings.ToLower(filepath.Ext(path))]
+}
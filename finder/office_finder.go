@@ -0,0 +1,64 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fdups/hasher"
+)
+
+// officeExtensions lists the extensions of the zip-based office documents
+// processed by the office finder.
+var officeExtensions = map[string]bool{
+	".docx": true,
+	".docm": true,
+	".xlsx": true,
+	".xlsm": true,
+	".pptx": true,
+	".pptm": true,
+	".odt":  true,
+	".ods":  true,
+	".odp":  true,
+	".odg":  true,
+}
+
+func init() {
+	Register(Registration{
+		Name:        "office",
+		Description: "Office Open XML and OpenDocument files, hashed by unpacked content",
+		New:         NewOfficeFinder,
+	})
+}
+
+// officeFinder finds duplicate office documents by comparing the unpacked
+// content of their zip containers.
+type officeFinder struct {
+	*baseFinder
+}
+
+// NewOfficeFinder creates a Finder that processes only zip-based office
+// documents in the target directory.
+//
+// Re-saving an unchanged document rewrites its container with new
+// timestamps and possibly different compression. This finder hashes the
+// unpacked parts instead, so such copies are detected as duplicates. Pass
+// hasher.WithIgnoreVolatileParts through WithHasherOptions to also ignore
+// document properties that change on every save, such as the modification
+// date and editing time.
+func NewOfficeFinder(targetDirectory string, options ...Option) Finder {
+	return &officeFinder{
+		baseFinder: newBaseFinder(
+			targetDirectory,
+			hasher.NewOfficeHasher,
+			acceptOfficeFiles,
+			options,
+		),
+	}
+}
+
+// acceptOfficeFiles is a FileFilter that accepts only zip-based office
+// documents by extension (case-insensitive).
+func acceptOfficeFiles(path string, _ os.FileInfo) bool {
+	return officeExtensions[strings.ToLower(filepath.Ext(path))]
+}
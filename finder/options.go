@@ -0,0 +1,33 @@
+package finder
+
+import (
+	"os"
+
+	"fdups/hasher"
+)
+
+// Option configures optional behavior of a Finder.
+//
+// Options are applied in order after the finder's defaults are set up.
+type Option func(*baseFinder)
+
+// WithFileFilter restricts a Finder to files accepted by filter.
+//
+// The filter is combined with the finder's own filter, so a FLAC finder
+// given a size filter still only processes FLAC files.
+func WithFileFilter(filter FileFilter) Option {
+	return func(f *baseFinder) {
+		previous := f.fileFilter
+		f.fileFilter = func(path string, info os.FileInfo) bool {
+			return previous(path, info) && filter(path, info)
+		}
+	}
+}
+
+// WithHasherOptions passes options to the hasher created by a Finder,
+// for example to select the hash algorithm.
+func WithHasherOptions(options ...hasher.Option) Option {
+	return func(f *baseFinder) {
+		f.hasherOptions = append(f.hasherOptions, options...)
+	}
+}
@@ -0,0 +1,278 @@
+package finder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"fdups/hasher"
+	"fdups/log"
+
+	"go.uber.org/zap"
+)
+
+// prefixBlockSize is the size of the blocks compared in the cheap stages of
+// prefix matching. Files smaller than this are not considered partial copies.
+const prefixBlockSize = 4096
+
+// PrefixMatch reports a file whose content is the beginning of larger files,
+// as left behind by an interrupted copy.
+type PrefixMatch struct {
+	// File is the partial copy.
+	File FileInfo `json:"file"`
+	// PartialCopyOf lists the larger files whose content starts with the
+	// content of File.
+	PartialCopyOf []FileInfo `json:"partialCopyOf"`
+}
+
+// PrefixResult holds the duplicate groups of a Find result alongside the
+// partial copies found among its files.
+type PrefixResult struct {
+	// Files maps file hashes to groups of files, as returned by Find.
+	Files map[string][]FileInfo `json:"files"`
+	// PartialCopies lists the files that are prefixes of larger files.
+	PartialCopies []PrefixMatch `json:"partialCopies"`
+}
+
+// prefixCandidate is a group of files with identical content taking part in
+// prefix matching.
+type prefixCandidate struct {
+	files []FileInfo
+	// path is the file read on behalf of the group.
+	path string
+	size int64
+	// fullHash is the SHA-256 hash of the content, computed on demand.
+	fullHash []byte
+}
+
+// FindPrefixMatches finds the files of a Find result whose content equals the
+// beginning of a larger file.
+//
+// Matching is staged to keep it cheap. Files are first bucketed by the hash
+// of their first 4 KiB. Within a bucket, the files are indexed by size and
+// the hash of their last 4 KiB, and each file is checked against the
+// smaller sizes by hashing its 4 KiB ending at each of them, so every file
+// is opened a bounded number of times rather than once per pair. Only pairs
+// passing both checks are compared in full, reading each larger file at
+// most once.
+//
+// Groups of files hashed by the default hasher have identical content, so
+// one file is read on behalf of the group. Other hashers consider files
+// with different content equal, such as FLAC files differing in tags, so
+// each of their files is matched on its own. Files smaller than 4 KiB and
+// virtual files are left out; files that cannot be read are logged and
+// skipped. The returned matches are sorted by path.
+func FindPrefixMatches(files map[string][]FileInfo) []PrefixMatch {
+	byHead := make(map[string][]*prefixCandidate)
+	for _, group := range files {
+		for _, candidate := range newPrefixCandidates(group) {
+			head, err := readBlock(candidate.path, 0)
+			if err != nil {
+				log.L().Warn("Skipped file (read failed)", zap.String("path", candidate.path), zap.Error(err))
+				continue
+			}
+			key := fmt.Sprintf("%x", sha256.Sum256(head))
+			byHead[key] = append(byHead[key], candidate)
+		}
+	}
+
+	matches := make(map[*prefixCandidate][]FileInfo)
+	for _, bucket := range byHead {
+		matchBucket(bucket, matches)
+	}
+
+	result := []PrefixMatch{}
+	for candidate, larger := range matches {
+		sortFilesByPath(larger)
+		for _, fileInfo := range candidate.files {
+			result = append(result, PrefixMatch{File: fileInfo, PartialCopyOf: larger})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].File.Path < result[j].File.Path })
+	return result
+}
+
+// newPrefixCandidates returns the candidates for a group of files sharing a
+// hash: a single one if the default hasher found their content identical,
+// and one per file otherwise. Virtual files and files smaller than
+// prefixBlockSize are left out.
+func newPrefixCandidates(group []FileInfo) []*prefixCandidate {
+	var candidates []*prefixCandidate
+	for _, fileInfo := range group {
+		if fileInfo.Virtual || fileInfo.Size < prefixBlockSize {
+			continue
+		}
+		if fileInfo.Hasher == "default" && len(candidates) > 0 {
+			candidates[0].files = append(candidates[0].files, fileInfo)
+			continue
+		}
+		candidate := &prefixCandidate{files: []FileInfo{fileInfo}, path: fileInfo.Path, size: fileInfo.Size}
+		// The raw content hash is already known for files hashed by the
+		// default hasher with SHA-256.
+		if fileInfo.Hasher == "default" && fileInfo.Algorithm == string(hasher.SHA256) {
+			candidate.fullHash, _ = hex.DecodeString(fileInfo.Hash)
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// tailIndex indexes prefix candidates by size and the SHA-256 hash of their
+// last block.
+type tailIndex struct {
+	// sizes lists the indexed sizes in ascending order.
+	sizes []int64
+	tails map[int64]map[[sha256.Size]byte][]*prefixCandidate
+}
+
+// newTailIndex reads the last block of each candidate in bucket, which is
+// sorted by size, and indexes it.
+func newTailIndex(bucket []*prefixCandidate) *tailIndex {
+	index := &tailIndex{tails: make(map[int64]map[[sha256.Size]byte][]*prefixCandidate)}
+	for _, candidate := range bucket {
+		tail, err := readBlock(candidate.path, candidate.size-prefixBlockSize)
+		if err != nil {
+			log.L().Warn("Skipped file (read failed)", zap.String("path", candidate.path), zap.Error(err))
+			continue
+		}
+		bySize, ok := index.tails[candidate.size]
+		if !ok {
+			bySize = make(map[[sha256.Size]byte][]*prefixCandidate)
+			index.tails[candidate.size] = bySize
+			index.sizes = append(index.sizes, candidate.size)
+		}
+		key := sha256.Sum256(tail)
+		bySize[key] = append(bySize[key], candidate)
+	}
+	return index
+}
+
+// smallerMatches returns the indexed candidates smaller than larger whose
+// last block equals the block of larger at the same offset, sorted by size.
+// larger is opened once, whatever the number of candidates.
+func (index *tailIndex) smallerMatches(larger *prefixCandidate) ([]*prefixCandidate, error) {
+	if len(index.sizes) == 0 || index.sizes[0] >= larger.size {
+		return nil, nil
+	}
+	file, err := os.Open(larger.path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var matches []*prefixCandidate
+	block := make([]byte, prefixBlockSize)
+	for _, size := range index.sizes {
+		if size >= larger.size {
+			break
+		}
+		if _, err := file.ReadAt(block, size-prefixBlockSize); err != nil {
+			return nil, err
+		}
+		matches = append(matches, index.tails[size][sha256.Sum256(block)]...)
+	}
+	return matches, nil
+}
+
+// matchBucket records in matches the larger candidates of bucket, whose
+// members share their first block, that each candidate is a prefix of.
+func matchBucket(bucket []*prefixCandidate, matches map[*prefixCandidate][]FileInfo) {
+	if len(bucket) < 2 {
+		return
+	}
+	sort.Slice(bucket, func(i, j int) bool { return bucket[i].size < bucket[j].size })
+	if bucket[0].size == bucket[len(bucket)-1].size {
+		return
+	}
+
+	index := newTailIndex(bucket)
+	for _, larger := range bucket {
+		smaller, err := index.smallerMatches(larger)
+		if err != nil {
+			log.L().Warn("Skipped file (read failed)", zap.String("path", larger.path), zap.Error(err))
+			continue
+		}
+		if len(smaller) == 0 {
+			continue
+		}
+
+		prefixes, err := matchPrefixes(larger, smaller)
+		if err != nil {
+			log.L().Warn("Skipped file (read failed)", zap.String("path", larger.path), zap.Error(err))
+			continue
+		}
+		for _, candidate := range prefixes {
+			matches[candidate] = append(matches[candidate], larger.files...)
+		}
+	}
+}
+
+// matchPrefixes returns the candidates of smaller, which are sorted by size,
+// whose full content equals the beginning of larger. larger is read once,
+// up to the size of the largest candidate.
+func matchPrefixes(larger *prefixCandidate, smaller []*prefixCandidate) ([]*prefixCandidate, error) {
+	file, err := os.Open(larger.path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var prefixes []*prefixCandidate
+	hash := sha256.New()
+	var read int64
+	for _, candidate := range smaller {
+		if _, err := io.CopyN(hash, file, candidate.size-read); err != nil {
+			return nil, err
+		}
+		read = candidate.size
+
+		fullHash, err := candidate.hash()
+		if err != nil {
+			log.L().Warn("Skipped file (read failed)", zap.String("path", candidate.path), zap.Error(err))
+			continue
+		}
+		// Sum leaves the running hash unchanged, so reading can continue.
+		if bytes.Equal(hash.Sum(nil), fullHash) {
+			prefixes = append(prefixes, candidate)
+		}
+	}
+	return prefixes, nil
+}
+
+// hash returns the SHA-256 hash of the candidate's content.
+func (c *prefixCandidate) hash() ([]byte, error) {
+	if c.fullHash != nil {
+		return c.fullHash, nil
+	}
+	file, err := os.Open(c.path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	c.fullHash = hash.Sum(nil)
+	return c.fullHash, nil
+}
+
+// readBlock reads prefixBlockSize bytes of the file at path from offset.
+func readBlock(path string, offset int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	block := make([]byte, prefixBlockSize)
+	if _, err := file.ReadAt(block, offset); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
@@ -0,0 +1,125 @@
+package finder
+
+import (
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// randomContent returns size pseudo-random bytes, the same for every call.
+func randomContent(size int) []byte {
+	content := make([]byte, size)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(content)
+	return content
+}
+
+// prefixMatchPaths returns the partial copies of matches, keyed by the path
+// of the partial copy, with paths relative to root.
+func prefixMatchPaths(t *testing.T, root string, matches []PrefixMatch) map[string][]string {
+	t.Helper()
+	paths := make(map[string][]string)
+	for _, match := range matches {
+		file := relativePaths(t, root, []FileInfo{match.File})[0]
+		paths[file] = relativePaths(t, root, match.PartialCopyOf)
+	}
+	return paths
+}
+
+func TestFindPrefixMatches(t *testing.T) {
+	full := randomContent(5 * prefixBlockSize)
+	middleChanged := append([]byte{}, full[:3*prefixBlockSize]...)
+	middleChanged[prefixBlockSize+100] ^= 1
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  map[string][]string
+	}{
+		{
+			name: "prefix",
+			files: map[string]string{
+				"full":    string(full),
+				"partial": string(full[:2*prefixBlockSize+100]),
+				"copy":    string(full[:2*prefixBlockSize+100]),
+			},
+			want: map[string][]string{"copy": {"full"}, "partial": {"full"}},
+		},
+		{
+			name: "prefix of several files",
+			files: map[string]string{
+				"full":    string(full),
+				"longer":  string(full[:4*prefixBlockSize]),
+				"partial": string(full[:3*prefixBlockSize]),
+			},
+			want: map[string][]string{"longer": {"full"}, "partial": {"full", "longer"}},
+		},
+		{
+			name: "same head and tail with different middle",
+			files: map[string]string{
+				"full":    string(full),
+				"partial": string(middleChanged),
+			},
+			want: map[string][]string{},
+		},
+		{
+			name: "files smaller than a block",
+			files: map[string]string{
+				"full":    string(full),
+				"partial": string(full[:prefixBlockSize-1]),
+			},
+			want: map[string][]string{},
+		},
+		{
+			name: "same size",
+			files: map[string]string{
+				"a": string(full),
+				"b": string(append(append([]byte{}, full[:len(full)-1]...), 0)),
+			},
+			want: map[string][]string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, test.files)
+			result := find(t, NewDefaultFinder(root))
+
+			if got := prefixMatchPaths(t, root, FindPrefixMatches(result)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("FindPrefixMatches() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFindPrefixMatchesChecksEachFileOfOtherHashers(t *testing.T) {
+	root := t.TempDir()
+	full := randomContent(3 * prefixBlockSize)
+	other := randomContent(4 * prefixBlockSize)[prefixBlockSize:]
+	writeTree(t, root, map[string]string{
+		"full":    string(full),
+		"partial": string(full[:2*prefixBlockSize]),
+		"other":   string(other[:2*prefixBlockSize]),
+	})
+
+	fileInfo := func(name string, hasherName string) FileInfo {
+		path := filepath.Join(root, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return FileInfo{Name: name, Path: path, Size: info.Size(), Hash: name, Hasher: hasherName}
+	}
+	// A hasher other than the default one put files with different content
+	// in one group, so only the file that is a prefix may be reported.
+	result := map[string][]FileInfo{
+		"flac:a": {fileInfo("other", "flac"), fileInfo("partial", "flac")},
+		"flac:b": {fileInfo("full", "flac")},
+	}
+
+	want := map[string][]string{"partial": {"full"}}
+	if got := prefixMatchPaths(t, root, FindPrefixMatches(result)); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindPrefixMatches() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package finder
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Registration describes a Finder implementation available by name.
+//
+// Most registered finders share the directory walking and worker pool of
+// the built-in finders and differ only in which files they accept and how
+// those files are hashed, given by Filter and Hasher. Finders that need
+// more control provide New instead.
+type Registration struct {
+	// Name is the unique name the finder is registered under.
+	Name string
+	// Description is a short, human-readable summary of the finder.
+	Description string
+	// Filter selects the files the finder processes.
+	Filter FileFilter
+	// Hasher creates the hasher applied to each accepted file.
+	Hasher HasherConstructor
+	// New creates the finder. When set, Filter and Hasher are not used.
+	New func(targetDirectory string, options ...Option) Finder
+}
+
+var (
+	registry     = make(map[string]Registration)
+	registryLock sync.RWMutex
+)
+
+// Register makes a finder available by name.
+//
+// Register is intended to be called from init functions. It panics if the
+// name is empty, already registered, or the registration lacks both a
+// constructor and a filter and hasher.
+func Register(registration Registration) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	buildable := registration.New != nil || (registration.Filter != nil && registration.Hasher != nil)
+	if registration.Name == "" || !buildable {
+		panic("finder: Register called with incomplete registration")
+	}
+	if _, exists := registry[registration.Name]; exists {
+		panic(fmt.Sprintf("finder: Register called twice for %q", registration.Name))
+	}
+	registry[registration.Name] = registration
+}
+
+// Lookup returns the finder registered under name.
+// The boolean result reports whether such a finder exists.
+func Lookup(name string) (Registration, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	registration, ok := registry[name]
+	return registration, ok
+}
+
+// Registrations returns all registered finders sorted by name.
+func Registrations() []Registration {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	registrations := make([]Registration, 0, len(registry))
+	for _, registration := range registry {
+		registrations = append(registrations, registration)
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].Name < registrations[j].Name
+	})
+	return registrations
+}
+
+// New creates the finder registered under name for targetDirectory.
+// Returns an error if no finder is registered under name.
+func New(name, targetDirectory string, options ...Option) (Finder, error) {
+	registration, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown finder %q", name)
+	}
+	if registration.New != nil {
+		return registration.New(targetDirectory, options...), nil
+	}
+	return newBaseFinder(targetDirectory, registration.Hasher, registration.Filter, options), nil
+}
@@ -0,0 +1,159 @@
+package finder
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fdups/hasher"
+)
+
+// DefaultMaxDistance is the default maximum Hamming distance between the
+// perceptual hashes of images considered similar.
+const DefaultMaxDistance = 10
+
+// perceptualHashBits is the number of bits in a perceptual hash.
+const perceptualHashBits = 64
+
+// decodableImageExtensions lists the extensions of the image files the
+// similar-images finder can decode.
+var decodableImageExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".jpe":  true,
+	".png":  true,
+	".gif":  true,
+}
+
+func init() {
+	Register(Registration{
+		Name:        "similar-images",
+		Description: "JPEG, PNG and GIF files, grouped by visual similarity",
+		New:         NewSimilarImagesFinder,
+	})
+}
+
+// similarImagesFinder finds visually similar images by comparing their
+// perceptual hashes.
+type similarImagesFinder struct {
+	*baseFinder
+}
+
+// NewSimilarImagesFinder creates a SimilarityFinder that processes JPEG, PNG
+// and GIF files in the target directory.
+//
+// Each image is decoded and reduced to a perceptual hash. Find groups images
+// whose hashes are identical, while FindSimilar also groups images whose
+// hashes differ in at most the number of bits set with WithMaxDistance,
+// DefaultMaxDistance by default. This catches resized and recompressed
+// copies that exact hashing misses.
+func NewSimilarImagesFinder(targetDirectory string, options ...Option) Finder {
+	options = append([]Option{WithMaxDistance(DefaultMaxDistance)}, options...)
+	return &similarImagesFinder{
+		baseFinder: newBaseFinder(
+			targetDirectory,
+			hasher.NewPerceptualHasher,
+			acceptDecodableImages,
+			options,
+		),
+	}
+}
+
+// WithMaxDistance sets the maximum Hamming distance between the perceptual
+// hashes of images grouped by a similar-images finder. Other finders ignore
+// this option.
+func WithMaxDistance(distance int) Option {
+	return func(f *baseFinder) {
+		f.maxDistance = distance
+	}
+}
+
+func (f *similarImagesFinder) FindSimilar() (error, []SimilarityGroup) {
+	err, result := f.Find()
+	if err != nil {
+		return err, nil
+	}
+
+	var files []FileInfo
+	for _, group := range result {
+		files = append(files, group...)
+	}
+	sortFilesByPath(files)
+
+	hashes := make([]uint64, len(files))
+	tree := &bkTree{}
+	for i, fileInfo := range files {
+		hash, err := parsePerceptualHash(fileInfo.Hash)
+		if err != nil {
+			return fmt.Errorf("invalid perceptual hash for %q: %w", fileInfo.Path, err), nil
+		}
+		hashes[i] = hash
+		tree.insert(hash, i)
+	}
+
+	return nil, clusterByDistance(files, hashes, tree, f.maxDistance)
+}
+
+// clusterByDistance groups files around reference files. Taking files in
+// order, each file not yet grouped becomes the reference of a new group
+// joined by all ungrouped files within maxDistance of it, so every member
+// of a group is similar to its reference.
+func clusterByDistance(files []FileInfo, hashes []uint64, tree *bkTree, maxDistance int) []SimilarityGroup {
+	grouped := make([]bool, len(files))
+	groups := []SimilarityGroup{}
+
+	for reference := range files {
+		if grouped[reference] {
+			continue
+		}
+
+		type match struct{ item, distance int }
+		var matches []match
+		tree.search(hashes[reference], maxDistance, func(item, distance int) {
+			if !grouped[item] && item != reference {
+				matches = append(matches, match{item, distance})
+			}
+		})
+		if len(matches) == 0 {
+			continue
+		}
+		sort.Slice(matches, func(i, j int) bool {
+			if matches[i].distance != matches[j].distance {
+				return matches[i].distance < matches[j].distance
+			}
+			return matches[i].item < matches[j].item
+		})
+
+		grouped[reference] = true
+		group := SimilarityGroup{Files: []SimilarFile{{FileInfo: files[reference], Similarity: 1}}}
+		for _, m := range matches {
+			grouped[m.item] = true
+			similarity := 1 - float64(m.distance)/perceptualHashBits
+			group.Files = append(group.Files, SimilarFile{FileInfo: files[m.item], Similarity: similarity})
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// parsePerceptualHash decodes a hexadecimal perceptual hash.
+func parsePerceptualHash(s string) (uint64, error) {
+	hash, err := hex.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(hash) != perceptualHashBits/8 {
+		return 0, fmt.Errorf("expected %d bytes, got %d", perceptualHashBits/8, len(hash))
+	}
+	return binary.BigEndian.Uint64(hash), nil
+}
+
+// acceptDecodableImages is a FileFilter that accepts only JPEG, PNG and GIF
+// files by extension (case-insensitive).
+func acceptDecodableImages(path string, _ os.FileInfo) bool {
+	return decodableImageExtensions[strings.ToLower(filepath.Ext(path))]
+}
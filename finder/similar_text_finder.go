@@ -0,0 +1,242 @@
+package finder
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fdups/hasher"
+)
+
+// DefaultMinSimilarity is the default minimum estimated Jaccard similarity
+// of documents considered similar.
+const DefaultMinSimilarity = 0.8
+
+// lshRecall is the minimum probability with which LSH banding makes two
+// documents exactly at the similarity threshold candidates for comparison.
+const lshRecall = 0.95
+
+// textExtensions lists the extensions of the text files processed by the
+// similar-text finder.
+var textExtensions = map[string]bool{
+	".txt":      true,
+	".text":     true,
+	".md":       true,
+	".markdown": true,
+	".rst":      true,
+	".adoc":     true,
+	".org":      true,
+	".tex":      true,
+	".csv":      true,
+	".htm":      true,
+	".html":     true,
+}
+
+func init() {
+	Register(Registration{
+		Name:        "similar-text",
+		Description: "Text files, grouped by shared wording",
+		New:         NewSimilarTextFinder,
+	})
+}
+
+// similarTextFinder finds near-duplicate text documents by comparing their
+// MinHash signatures.
+type similarTextFinder struct {
+	*baseFinder
+}
+
+// NewSimilarTextFinder creates a SimilarityFinder that processes text files
+// in the target directory.
+//
+// Each document is reduced to a MinHash signature of its word shingles.
+// Find groups documents with identical signatures, while FindSimilar groups
+// documents around a reference document, the first by path, joined by the
+// documents whose estimated Jaccard similarity to it reaches the threshold
+// set with WithMinSimilarity, DefaultMinSimilarity by default. Candidate
+// pairs are found with locality-sensitive hashing, so documents are not
+// compared with every other document. Documents without words are listed
+// as unparseable in the Diagnostics.
+func NewSimilarTextFinder(targetDirectory string, options ...Option) Finder {
+	options = append([]Option{WithMinSimilarity(DefaultMinSimilarity)}, options...)
+	return &similarTextFinder{
+		baseFinder: newBaseFinder(
+			targetDirectory,
+			hasher.NewMinHashHasher,
+			acceptTextFiles,
+			options,
+		),
+	}
+}
+
+// WithMinSimilarity sets the minimum estimated Jaccard similarity, between 0
+// and 1, of documents grouped by a similar-text finder. Other finders ignore
+// this option.
+func WithMinSimilarity(threshold float64) Option {
+	return func(f *baseFinder) {
+		f.minSimilarity = threshold
+	}
+}
+
+func (f *similarTextFinder) FindSimilar() (error, []SimilarityGroup) {
+	err, result := f.Find()
+	if err != nil {
+		return err, nil
+	}
+
+	var files []FileInfo
+	for _, group := range result {
+		files = append(files, group...)
+	}
+	sortFilesByPath(files)
+
+	signatures := make([][]byte, len(files))
+	for i, fileInfo := range files {
+		signature, err := hex.DecodeString(fileInfo.Hash)
+		if err != nil {
+			return fmt.Errorf("invalid MinHash signature for %q: %w", fileInfo.Path, err), nil
+		}
+		signatures[i] = signature
+	}
+
+	similarity := f.hasher.(hasher.SimilarityHasher).Similarity
+	neighbors := make([][]int, len(files))
+	for pair := range lshCandidates(signatures, lshRows(f.minSimilarity, hasher.MinHashPermutations)) {
+		score, err := similarity(signatures[pair[0]], signatures[pair[1]])
+		if err != nil {
+			return fmt.Errorf("failed to compare %q and %q: %w", files[pair[0]].Path, files[pair[1]].Path, err), nil
+		}
+		if score >= f.minSimilarity {
+			neighbors[pair[0]] = append(neighbors[pair[0]], pair[1])
+			neighbors[pair[1]] = append(neighbors[pair[1]], pair[0])
+		}
+	}
+
+	groups := []SimilarityGroup{}
+	for _, members := range clusterByReference(neighbors) {
+		group, err := pairwiseGroup(files, signatures, members, similarity)
+		if err != nil {
+			return err, nil
+		}
+		groups = append(groups, group)
+	}
+	return nil, groups
+}
+
+// clusterByReference groups the items whose similar items are listed in
+// neighbors around reference items, just like clusterByDistance. Taking
+// items in order, each item not yet grouped becomes the reference of a new
+// group joined by all of its ungrouped neighbors, so every member of a group
+// is similar to its reference, rather than only to some other member. The
+// members of each group are returned in ascending order, reference first.
+func clusterByReference(neighbors [][]int) [][]int {
+	grouped := make([]bool, len(neighbors))
+	var groups [][]int
+
+	for reference := range neighbors {
+		if grouped[reference] {
+			continue
+		}
+		members := []int{reference}
+		for _, item := range neighbors[reference] {
+			if !grouped[item] {
+				members = append(members, item)
+			}
+		}
+		if len(members) < 2 {
+			continue
+		}
+		// Neighbors before the reference are grouped already, as the
+		// reference would otherwise have joined their group.
+		sort.Ints(members)
+		for _, item := range members {
+			grouped[item] = true
+		}
+		groups = append(groups, members)
+	}
+	return groups
+}
+
+// lshRows returns the number of signature values per band for LSH banding
+// of signatures with the given number of values. It picks the most
+// selective banding under which documents at threshold still become
+// candidates with probability lshRecall.
+func lshRows(threshold float64, values int) int {
+	rows := 1
+	for r := 2; r <= values; r++ {
+		bands := values / r
+		if 1-math.Pow(1-math.Pow(threshold, float64(r)), float64(bands)) < lshRecall {
+			break
+		}
+		rows = r
+	}
+	return rows
+}
+
+// lshCandidates returns the pairs of signatures, as ordered index pairs,
+// that agree on all values of at least one band of rows values.
+func lshCandidates(signatures [][]byte, rows int) map[[2]int]bool {
+	candidates := make(map[[2]int]bool)
+	if len(signatures) == 0 {
+		return candidates
+	}
+
+	const valueSize = 4
+	bands := len(signatures[0]) / valueSize / rows
+	for band := 0; band < bands; band++ {
+		buckets := make(map[string][]int)
+		for i, signature := range signatures {
+			key := string(signature[band*rows*valueSize : (band+1)*rows*valueSize])
+			buckets[key] = append(buckets[key], i)
+		}
+		for _, bucket := range buckets {
+			for a := 0; a < len(bucket); a++ {
+				for b := a + 1; b < len(bucket); b++ {
+					candidates[[2]int{bucket[a], bucket[b]}] = true
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+// pairwiseGroup builds the SimilarityGroup of the files at the indexes in
+// members, which are in ascending order, scoring every pair of members.
+func pairwiseGroup(files []FileInfo, signatures [][]byte, members []int, similarity func(a, b []byte) (float64, error)) (SimilarityGroup, error) {
+	reference := members[0]
+	group := SimilarityGroup{Files: []SimilarFile{{FileInfo: files[reference], Similarity: 1}}}
+
+	for i, a := range members {
+		for _, b := range members[i+1:] {
+			score, err := similarity(signatures[a], signatures[b])
+			if err != nil {
+				return SimilarityGroup{}, fmt.Errorf("failed to compare %q and %q: %w", files[a].Path, files[b].Path, err)
+			}
+			group.Pairs = append(group.Pairs, SimilarPair{
+				Paths:      [2]string{files[a].Path, files[b].Path},
+				Similarity: score,
+			})
+			if a == reference {
+				group.Files = append(group.Files, SimilarFile{FileInfo: files[b], Similarity: score})
+			}
+		}
+	}
+
+	sort.SliceStable(group.Files[1:], func(i, j int) bool {
+		return group.Files[1+i].Similarity > group.Files[1+j].Similarity
+	})
+	sort.SliceStable(group.Pairs, func(i, j int) bool {
+		return group.Pairs[i].Similarity > group.Pairs[j].Similarity
+	})
+	return group, nil
+}
+
+// acceptTextFiles is a FileFilter that accepts only text documents by
+// extension (case-insensitive).
+func acceptTextFiles(path string, _ os.FileInfo) bool {
+	return textExtensions[strings.ToLower(filepath.Ext(path))]
+}
@@ -0,0 +1,112 @@
+package finder
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// signature returns a MinHash-style signature holding values.
+func signature(values ...uint32) []byte {
+	var s []byte
+	for _, value := range values {
+		s = binary.BigEndian.AppendUint32(s, value)
+	}
+	return s
+}
+
+func TestLshRows(t *testing.T) {
+	tests := []struct {
+		threshold float64
+		values    int
+		rows      int
+	}{
+		{0, 128, 1},
+		{0.5, 128, 3},
+		{0.8, 128, 7},
+		{0.9, 128, 12},
+		{0.95, 128, 18},
+		{1, 128, 128},
+		{0.8, 4, 1},
+	}
+	for _, test := range tests {
+		if rows := lshRows(test.threshold, test.values); rows != test.rows {
+			t.Errorf("lshRows(%v, %d) = %d, want %d", test.threshold, test.values, rows, test.rows)
+		}
+	}
+}
+
+func TestLshCandidates(t *testing.T) {
+	tests := []struct {
+		name       string
+		signatures [][]byte
+		rows       int
+		candidates map[[2]int]bool
+	}{
+		{"no signatures", nil, 2, map[[2]int]bool{}},
+		{
+			"shared first band",
+			[][]byte{signature(1, 2, 3, 4), signature(1, 2, 5, 6), signature(7, 8, 9, 10)},
+			2,
+			map[[2]int]bool{{0, 1}: true},
+		},
+		{
+			"shared second band",
+			[][]byte{signature(1, 2, 3, 4), signature(7, 8, 9, 10), signature(5, 6, 3, 4)},
+			2,
+			map[[2]int]bool{{0, 2}: true},
+		},
+		{
+			"partial band match",
+			[][]byte{signature(1, 2, 3, 4), signature(1, 5, 3, 6)},
+			2,
+			map[[2]int]bool{},
+		},
+		{
+			"single-value bands",
+			[][]byte{signature(1, 2, 3, 4), signature(1, 5, 3, 6)},
+			1,
+			map[[2]int]bool{{0, 1}: true},
+		},
+		{
+			"bucket of three",
+			[][]byte{signature(1, 2, 3, 4), signature(1, 2, 5, 6), signature(1, 2, 7, 8)},
+			2,
+			map[[2]int]bool{{0, 1}: true, {0, 2}: true, {1, 2}: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			candidates := lshCandidates(test.signatures, test.rows)
+			if !reflect.DeepEqual(candidates, test.candidates) {
+				t.Errorf("lshCandidates() = %v, want %v", candidates, test.candidates)
+			}
+		})
+	}
+}
+
+func TestClusterByReference(t *testing.T) {
+	tests := []struct {
+		name      string
+		neighbors [][]int
+		groups    [][]int
+	}{
+		{"no items", nil, nil},
+		{"no neighbors", [][]int{nil, nil, nil}, nil},
+		{"pair", [][]int{{1}, {0}}, [][]int{{0, 1}}},
+		{"star", [][]int{{2, 1, 3}, {0}, {0}, {0}}, [][]int{{0, 1, 2, 3}}},
+		// 0 and 2 are both similar to 1 but not to each other, so they are
+		// not chained into one group.
+		{"chain", [][]int{{1}, {0, 2}, {1}}, [][]int{{0, 1}}},
+		{"chain continues", [][]int{{1}, {0, 2}, {1, 3}, {2}}, [][]int{{0, 1}, {2, 3}}},
+		{"separate groups", [][]int{{2}, {3}, {0}, {1}}, [][]int{{0, 2}, {1, 3}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			groups := clusterByReference(test.neighbors)
+			if !reflect.DeepEqual(groups, test.groups) {
+				t.Errorf("clusterByReference() = %v, want %v", groups, test.groups)
+			}
+		})
+	}
+}
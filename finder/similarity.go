@@ -0,0 +1,43 @@
+package finder
+
+// SimilarFile is a member of a SimilarityGroup.
+type SimilarFile struct {
+	FileInfo
+	// Similarity is the similarity of the file to the first file of its
+	// group, ranging from 0 (unrelated) to 1 (indistinguishable).
+	Similarity float64 `json:"similarity"`
+}
+
+// SimilarPair records the similarity of two members of a SimilarityGroup.
+type SimilarPair struct {
+	// Paths are the paths of the two files in lexical order.
+	Paths [2]string `json:"paths"`
+	// Similarity is the similarity of the two files, ranging from 0
+	// (unrelated) to 1 (indistinguishable).
+	Similarity float64 `json:"similarity"`
+}
+
+// SimilarityGroup holds files that are near-duplicates of each other.
+//
+// Unlike the groups returned by Find, members of a SimilarityGroup need not
+// have identical hashes. The first file is the reference the group was built
+// around, and the other files follow in order of decreasing similarity.
+type SimilarityGroup struct {
+	// Files are the members of the group.
+	Files []SimilarFile `json:"files"`
+	// Pairs holds the similarity of every pair of members, most similar
+	// first. It is only set by finders that compare members pairwise.
+	Pairs []SimilarPair `json:"pairs,omitempty"`
+}
+
+// SimilarityFinder is a Finder that also detects near-duplicate files.
+type SimilarityFinder interface {
+	Finder
+
+	// FindSimilar scans the target directory and returns groups of files
+	// similar enough to count as duplicates. Files without a near-duplicate
+	// are left out. Groups are sorted by the path of their first file.
+	//
+	// Returns an error if directory traversal or file processing fails.
+	FindSimilar() (error, []SimilarityGroup)
+}
@@ -0,0 +1,54 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fdups/hasher"
+)
+
+// structuredExtensions lists the extensions of the JSON and YAML documents
+// processed by the structured finder.
+var structuredExtensions = map[string]bool{
+	".json": true,
+	".yaml": true,
+	".yml":  true,
+}
+
+func init() {
+	Register(Registration{
+		Name:        "structured",
+		Description: "JSON and YAML files, hashed by data ignoring key order and formatting",
+		New:         NewStructuredFinder,
+	})
+}
+
+// structuredFinder finds duplicate JSON and YAML documents by comparing the
+// data they hold.
+type structuredFinder struct {
+	*baseFinder
+}
+
+// NewStructuredFinder creates a Finder that processes only JSON and YAML
+// files in the target directory.
+//
+// Generated configs that differ only in key order, indentation or number
+// formatting are detected as duplicates. Files that fail to parse are
+// compared by raw content rather than aborting the scan.
+func NewStructuredFinder(targetDirectory string, options ...Option) Finder {
+	return &structuredFinder{
+		baseFinder: newBaseFinder(
+			targetDirectory,
+			hasher.NewStructuredHasher,
+			acceptStructuredFiles,
+			options,
+		),
+	}
+}
+
+// acceptStructuredFiles is a FileFilter that accepts only JSON and YAML files
+// by extension (case-insensitive).
+func acceptStructuredFiles(path string, _ os.FileInfo) bool {
+	return structuredExtensions[strings.ToLower(filepath.Ext(path))]
+}
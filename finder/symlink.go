@@ -0,0 +1,169 @@
+package finder
+
+import (
+	"fmt"
+	"os"
+
+	"fdups/log"
+
+	"go.uber.org/zap"
+)
+
+// SymlinkPolicy determines how a Finder treats the symbolic links it
+// encounters while walking the target directory.
+type SymlinkPolicy string
+
+const (
+	// SkipSymlinks ignores symbolic links. This is the default.
+	SkipSymlinks SymlinkPolicy = "skip"
+	// ReportSymlinks lists symbolic links in the Diagnostics without
+	// processing their targets.
+	ReportSymlinks SymlinkPolicy = "report"
+	// FollowSymlinks processes the targets of symbolic links, walking
+	// linked directories as if they were part of the tree.
+	FollowSymlinks SymlinkPolicy = "follow"
+)
+
+// SymlinkPolicies returns all supported symlink policies.
+func SymlinkPolicies() []SymlinkPolicy {
+	return []SymlinkPolicy{SkipSymlinks, ReportSymlinks, FollowSymlinks}
+}
+
+// ParseSymlinkPolicy returns the SymlinkPolicy named by name.
+// Returns an error if the policy is not supported.
+func ParseSymlinkPolicy(name string) (SymlinkPolicy, error) {
+	for _, policy := range SymlinkPolicies() {
+		if string(policy) == name {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported symlink policy %q", name)
+}
+
+// Symlink describes a symbolic link found while walking.
+type Symlink struct {
+	// Path is the path of the link.
+	Path string `json:"path"`
+	// Target is the destination stored in the link.
+	Target string `json:"target"`
+	// Error describes why the target could not be resolved, for dangling links.
+	Error string `json:"error,omitempty"`
+}
+
+// inodeIdentity identifies a file or directory by device and inode number.
+type inodeIdentity struct {
+	device uint64
+	inode  uint64
+}
+
+// WithSymlinks sets how a Finder treats symbolic links while walking.
+//
+// Links whose target does not exist are listed as dangling in the
+// Diagnostics unless links are skipped. When following links, each
+// directory is walked at most once, identified by device and inode number,
+// so links pointing back up the tree cannot cause endless loops and a
+// directory reachable through several links is reported under the first
+// path it was reached by. Likewise, a file reached through a link is
+// processed only under the first path it was reached by, so a link is not
+// reported as a duplicate of its own target; hardlinks within the tree are
+// still reported as such. The target directory itself is always followed.
+// Links in a file list are always followed.
+func WithSymlinks(policy SymlinkPolicy) Option {
+	return func(f *baseFinder) {
+		f.symlinks = policy
+	}
+}
+
+// processSymlink handles the symbolic link at path according to the
+// symlink policy of the finder.
+func (f *baseFinder) processSymlink(path string, channel chan<- walkDirectoryYield) error {
+	if f.symlinks == SkipSymlinks {
+		log.L().Debug("Skipped symlink", zap.String("path", path))
+		return nil
+	}
+
+	target, _ := os.Readlink(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		log.L().Warn("Dangling symlink", zap.String("path", path), zap.String("target", target), zap.Error(err))
+		f.diagnostics.Dangling = append(f.diagnostics.Dangling, Symlink{Path: path, Target: target, Error: err.Error()})
+		return nil
+	}
+	if f.symlinks == ReportSymlinks {
+		log.L().Debug("Reported symlink", zap.String("path", path), zap.String("target", target))
+		f.diagnostics.Symlinks = append(f.diagnostics.Symlinks, Symlink{Path: path, Target: target})
+		return nil
+	}
+
+	if !info.IsDir() {
+		if !f.claimFile(path, info, true) {
+			return nil
+		}
+		log.L().Debug("Following symlink", zap.String("path", path), zap.String("target", target))
+		return f.processFile(path, info, channel)
+	}
+	if _, _, ok := fileIdentity(info); !ok {
+		log.L().Warn("Skipped symlinked directory (cannot detect cycles)", zap.String("path", path))
+		return nil
+	}
+	log.L().Debug("Following symlinked directory", zap.String("path", path), zap.String("target", target))
+	return f.walkTree(path, channel)
+}
+
+// enterDirectory reports whether the directory described by info should be
+// walked. When following symlinks, a directory is only walked the first
+// time it is reached.
+func (f *baseFinder) enterDirectory(path string, info os.FileInfo) bool {
+	if f.symlinks != FollowSymlinks {
+		return true
+	}
+	device, inode, ok := fileIdentity(info)
+	if !ok {
+		return true
+	}
+
+	identity := inodeIdentity{device: device, inode: inode}
+	if f.visitedDirectories[identity] {
+		log.L().Debug("Skipped directory (already walked)", zap.String("path", path))
+		return false
+	}
+	if f.visitedDirectories == nil {
+		f.visitedDirectories = make(map[inodeIdentity]bool)
+	}
+	f.visitedDirectories[identity] = true
+	return true
+}
+
+// claimFile reports whether the file described by info, reached at path
+// directly or through a symbolic link, should be processed. When following
+// symlinks, a file reached through a link is only processed the first time
+// it is reached, whether through a link or directly; files reached directly
+// more than once are hardlinks and are all processed.
+func (f *baseFinder) claimFile(path string, info os.FileInfo, throughLink bool) bool {
+	if f.symlinks != FollowSymlinks {
+		return true
+	}
+	device, inode, ok := fileIdentity(info)
+	if !ok {
+		return true
+	}
+
+	identity := inodeIdentity{device: device, inode: inode}
+	linkedBefore, seen := f.visitedFiles[identity]
+	switch {
+	case !seen:
+		if f.visitedFiles == nil {
+			f.visitedFiles = make(map[inodeIdentity]bool)
+		}
+		f.visitedFiles[identity] = throughLink
+		return true
+	case throughLink:
+		log.L().Debug("Skipped symlink (target already processed)", zap.String("path", path))
+		return false
+	case linkedBefore:
+		log.L().Debug("Skipped file (already processed through a symlink)", zap.String("path", path))
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,93 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// symlink creates a symbolic link at name, relative to root, pointing to
+// target.
+func symlink(t *testing.T, root, target, name string) {
+	t.Helper()
+	if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// allPaths returns the sorted paths of all files of result relative to root.
+func allPaths(t *testing.T, root string, result map[string][]FileInfo) []string {
+	t.Helper()
+	var files []FileInfo
+	for _, group := range result {
+		files = append(files, group...)
+	}
+	return relativePaths(t, root, files)
+}
+
+func TestWithSymlinksFollowsDirectoryLoopOnce(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"dir/file": "content", "other": "other"})
+	symlink(t, root, "..", "dir/parent")
+	symlink(t, root, ".", "dir/self")
+
+	f := NewDefaultFinder(root, WithSymlinks(FollowSymlinks))
+	result := find(t, f)
+
+	want := []string{"dir/file", "other"}
+	if got := allPaths(t, root, result); !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() files = %v, want %v", got, want)
+	}
+	if got := groupedPaths(t, root, result); len(got) != 0 {
+		t.Errorf("Find() groups = %v, want none", got)
+	}
+}
+
+func TestWithSymlinksProcessesLinkedFileOnce(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"target/file": "content"})
+	symlink(t, root, "target/file", "a")
+	symlink(t, root, "target/file", "b")
+
+	tests := []struct {
+		name   string
+		policy SymlinkPolicy
+		want   []string
+	}{
+		{"skip", SkipSymlinks, []string{"target/file"}},
+		// The file is reached through a first, so it is processed under
+		// that path only rather than as a duplicate of itself.
+		{"follow", FollowSymlinks, []string{"a"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := find(t, NewDefaultFinder(root, WithSymlinks(test.policy)))
+			if got := allPaths(t, root, result); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Find() files = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWithSymlinksReportsLinks(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"file": "content"})
+	symlink(t, root, "file", "link")
+	symlink(t, root, "missing", "dangling")
+
+	f := NewDefaultFinder(root, WithSymlinks(ReportSymlinks))
+	result := find(t, f)
+
+	if got, want := allPaths(t, root, result), []string{"file"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() files = %v, want %v", got, want)
+	}
+	diagnostics := f.(DiagnosticsReporter).Diagnostics()
+	wantSymlinks := []Symlink{{Path: filepath.Join(root, "link"), Target: "file"}}
+	if !reflect.DeepEqual(diagnostics.Symlinks, wantSymlinks) {
+		t.Errorf("Diagnostics().Symlinks = %v, want %v", diagnostics.Symlinks, wantSymlinks)
+	}
+	if len(diagnostics.Dangling) != 1 || diagnostics.Dangling[0].Path != filepath.Join(root, "dangling") {
+		t.Errorf("Diagnostics().Dangling = %v, want the dangling link", diagnostics.Dangling)
+	}
+}
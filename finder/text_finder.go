@@ -0,0 +1,35 @@
+package finder
+
+import (
+	"fdups/hasher"
+)
+
+func init() {
+	Register(Registration{
+		Name:        "text",
+		Description: "All files, text hashed ignoring line endings and trailing whitespace",
+		New:         NewTextFinder,
+	})
+}
+
+// NewTextFinder creates a Finder that processes all files in the target
+// directory, hashing those detected as text with the text hasher and the
+// rest by raw content.
+//
+// Text is detected by sniffing content rather than by extension, so source
+// files, configs and logs are covered whatever they are named. Copies of a
+// text file that were moved between Windows and Unix systems, and differ
+// only in line endings, trailing whitespace or a byte order mark, are
+// detected as duplicates. The normalizations applied are selected with
+// hasher.WithTextNormalization passed through WithHasherOptions.
+func NewTextFinder(targetDirectory string, options ...Option) Finder {
+	return NewCompositeFinder(targetDirectory, textRoutes(), hasher.NewDefaultHasher, options...)
+}
+
+// textRoutes returns the routes of the registered "text" finder.
+func textRoutes() []Route {
+	return []Route{{
+		MIMETypes: []string{"text/plain", "text/html", "text/xml"},
+		Hasher:    hasher.NewTextHasher,
+	}}
+}
@@ -0,0 +1,64 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// writeTree creates the files in contents, keyed by slash-separated paths
+// relative to root, along with their parent directories.
+func writeTree(t *testing.T, root string, contents map[string]string) {
+	t.Helper()
+	for name, content := range contents {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// find runs f and returns its result.
+func find(t *testing.T, f Finder) map[string][]FileInfo {
+	t.Helper()
+	err, result := f.Find()
+	if err != nil {
+		t.Fatalf("Find() failed: %v", err)
+	}
+	return result
+}
+
+// relativePaths returns the sorted, slash-separated paths of files relative
+// to root.
+func relativePaths(t *testing.T, root string, files []FileInfo) []string {
+	t.Helper()
+	paths := []string{}
+	for _, fileInfo := range files {
+		relative, err := filepath.Rel(root, fileInfo.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, filepath.ToSlash(relative))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// groupedPaths returns the paths of each group of result with more than one
+// member, relative to root and sorted.
+func groupedPaths(t *testing.T, root string, result map[string][]FileInfo) [][]string {
+	t.Helper()
+	groups := [][]string{}
+	for _, group := range result {
+		if len(group) < 2 {
+			continue
+		}
+		groups = append(groups, relativePaths(t, root, group))
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups
+}
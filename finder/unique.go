@@ -0,0 +1,42 @@
+package finder
+
+// UniqueFiles returns the files of a Find result whose content exists only once.
+//
+// A group counts as unique if it has a single member, or if all of its
+// members are hardlinks to the same inode. In the latter case, the member
+// with the lowest path represents the group. The returned slice is sorted
+// by path.
+func UniqueFiles(files map[string][]FileInfo) []FileInfo {
+	unique := []FileInfo{}
+	for _, group := range files {
+		if isSingleInode(group) {
+			unique = append(unique, lowestPath(group))
+		}
+	}
+	sortFilesByPath(unique)
+	return unique
+}
+
+// isSingleInode reports whether all files in group refer to the same inode.
+func isSingleInode(group []FileInfo) bool {
+	if len(group) == 1 {
+		return true
+	}
+	for _, fileInfo := range group {
+		if fileInfo.Inode == 0 || fileInfo.Device != group[0].Device || fileInfo.Inode != group[0].Inode {
+			return false
+		}
+	}
+	return true
+}
+
+// lowestPath returns the file of group with the lexically lowest path.
+func lowestPath(group []FileInfo) FileInfo {
+	lowest := group[0]
+	for _, fileInfo := range group[1:] {
+		if fileInfo.Path < lowest.Path {
+			lowest = fileInfo
+		}
+	}
+	return lowest
+}
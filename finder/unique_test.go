@@ -0,0 +1,84 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUniqueFiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups [][]FileInfo
+		unique []string
+	}{
+		{"empty result", nil, []string{}},
+		{
+			"single files",
+			[][]FileInfo{{{Path: "/b"}}, {{Path: "/a"}}},
+			[]string{"/a", "/b"},
+		},
+		{
+			"duplicates",
+			[][]FileInfo{{{Path: "/a", Device: 1, Inode: 1}, {Path: "/b", Device: 1, Inode: 2}}},
+			[]string{},
+		},
+		{
+			"hardlinks",
+			[][]FileInfo{{{Path: "/b", Device: 1, Inode: 7}, {Path: "/a", Device: 1, Inode: 7}}},
+			[]string{"/a"},
+		},
+		{
+			"same inode on different devices",
+			[][]FileInfo{{{Path: "/a", Device: 1, Inode: 7}, {Path: "/b", Device: 2, Inode: 7}}},
+			[]string{},
+		},
+		{
+			"unknown inodes",
+			[][]FileInfo{{{Path: "/a"}, {Path: "/b"}}},
+			[]string{},
+		},
+		{
+			"hardlinks and a copy",
+			[][]FileInfo{{{Path: "/a", Device: 1, Inode: 7}, {Path: "/b", Device: 1, Inode: 7}, {Path: "/c", Device: 1, Inode: 8}}},
+			[]string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := make(map[string][]FileInfo)
+			for i, group := range test.groups {
+				files[string(rune('0'+i))] = group
+			}
+			unique := []string{}
+			for _, fileInfo := range UniqueFiles(files) {
+				unique = append(unique, fileInfo.Path)
+			}
+			if !reflect.DeepEqual(unique, test.unique) {
+				t.Errorf("UniqueFiles() = %v, want %v", unique, test.unique)
+			}
+		})
+	}
+}
+
+func TestUniqueFilesCountsHardlinksOnce(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"linked":      "linked content",
+		"copy/a":      "copied content",
+		"copy/b":      "copied content",
+		"lonely/file": "unique content",
+	})
+	if err := os.Link(filepath.Join(root, "linked"), filepath.Join(root, "link1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(root, "linked"), filepath.Join(root, "link2")); err != nil {
+		t.Fatal(err)
+	}
+
+	unique := relativePaths(t, root, UniqueFiles(find(t, NewDefaultFinder(root))))
+	if want := []string{"link1", "lonely/file"}; !reflect.DeepEqual(unique, want) {
+		t.Errorf("UniqueFiles() = %v, want %v", unique, want)
+	}
+}
@@ -0,0 +1,198 @@
+package finder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// DefaultDirectoryWorkers is the number of directories read concurrently
+// while walking, unless set with WithDirectoryWorkers.
+const DefaultDirectoryWorkers = 16
+
+// errWalkStopped is the error of directory reads abandoned because the walk
+// ended before they started.
+var errWalkStopped = errors.New("walk stopped")
+
+// WithDirectoryWorkers sets the number of directories a Finder reads
+// concurrently while walking the target directory. Values below one are
+// treated as one.
+//
+// More workers hide the latency of network filesystems and speed up trees
+// of many small directories; the order in which files are processed does
+// not depend on it.
+func WithDirectoryWorkers(workers int) Option {
+	return func(f *baseFinder) {
+		f.directoryWorkers = max(workers, 1)
+	}
+}
+
+// listedEntry is an entry of a directory listing along with its lstat result.
+type listedEntry struct {
+	name string
+	info os.FileInfo
+	err  error
+}
+
+// directoryListing is the content of the directory at path, which is
+// available once done is closed.
+type directoryListing struct {
+	path    string
+	done    chan struct{}
+	entries []listedEntry
+	err     error
+}
+
+// directoryReader reads directories in the background, with a bounded
+// number of reads running at once.
+type directoryReader struct {
+	workers chan struct{}
+	stop    chan struct{}
+}
+
+// newDirectoryReader creates a directoryReader running at most workers
+// reads at once. It must be stopped once the walk is over.
+func newDirectoryReader(workers int) *directoryReader {
+	return &directoryReader{
+		workers: make(chan struct{}, workers),
+		stop:    make(chan struct{}),
+	}
+}
+
+// read starts reading the directory at path, returning its listing. The
+// entries are sorted by name, as by os.ReadDir.
+func (r *directoryReader) read(path string) *directoryListing {
+	listing := &directoryListing{path: path, done: make(chan struct{})}
+	go func() {
+		defer close(listing.done)
+		select {
+		case r.workers <- struct{}{}:
+		case <-r.stop:
+			listing.err = errWalkStopped
+			return
+		}
+		defer func() { <-r.workers }()
+		listing.entries, listing.err = readDirectory(path)
+	}()
+	return listing
+}
+
+// close abandons the reads that have not started yet.
+func (r *directoryReader) close() {
+	close(r.stop)
+}
+
+// readDirectory reads the entries of the directory at path and lstats them.
+func readDirectory(path string) ([]listedEntry, error) {
+	directoryEntries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]listedEntry, len(directoryEntries))
+	for i, entry := range directoryEntries {
+		entries[i].name = entry.Name()
+		entries[i].info, entries[i].err = entry.Info()
+	}
+	return entries, nil
+}
+
+// walkTree walks the directory tree at root, resolving root first if it is
+// a symbolic link. Paths below a resolved root are reported relative to
+// root rather than to the directory it resolves to.
+//
+// Entries are processed in lexical order, depth first, just like
+// filepath.Walk would, while up to directoryWorkers of the directories
+// about to be walked are read ahead by the directory reader of the finder.
+func (f *baseFinder) walkTree(root string, channel chan<- walkDirectoryYield) error {
+	resolved, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		// Walking an unresolvable root reports the error.
+		resolved = root
+	}
+	info, err := os.Lstat(resolved)
+	if err != nil {
+		return f.processWalkEntry(root, nil, err, channel)
+	}
+
+	err = f.processWalkEntry(root, info, nil, channel)
+	if !info.IsDir() || err != nil {
+		if errors.Is(err, filepath.SkipDir) {
+			return nil
+		}
+		return err
+	}
+	return f.walkListing(root, info, f.directoryReader.read(resolved), channel)
+}
+
+// walkListing processes the entries of the directory at path, described by
+// info, whose content is read into listing. Entries below path are read
+// from the corresponding entries below the directory listing was read from.
+func (f *baseFinder) walkListing(path string, info os.FileInfo, listing *directoryListing, channel chan<- walkDirectoryYield) error {
+	<-listing.done
+	if listing.err != nil {
+		return f.processWalkEntry(path, info, listing.err, channel)
+	}
+
+	// Subdirectories likely to be walked are read ahead, so they can be read
+	// while the entries before them are processed. Whether to walk them is
+	// only decided once they are reached, so directories are claimed in the
+	// order they are walked in.
+	var ahead []int
+	for i, entry := range listing.entries {
+		if entry.err == nil && entry.info.IsDir() && f.mayEnterDirectory(entry.info) {
+			ahead = append(ahead, i)
+		}
+	}
+	subdirectories := make(map[int]*directoryListing)
+	readAhead := func() {
+		for len(ahead) > 0 && len(subdirectories) < f.directoryWorkers {
+			i := ahead[0]
+			ahead = ahead[1:]
+			subdirectories[i] = f.directoryReader.read(filepath.Join(listing.path, listing.entries[i].name))
+		}
+	}
+	readAhead()
+
+	for i, entry := range listing.entries {
+		entryPath := filepath.Join(path, entry.name)
+		var err error
+		switch {
+		case entry.err != nil:
+			err = f.processWalkEntry(entryPath, nil, entry.err, channel)
+		case entry.info.IsDir():
+			subdirectory, readingAhead := subdirectories[i]
+			delete(subdirectories, i)
+			err = f.processWalkEntry(entryPath, entry.info, nil, channel)
+			if err == nil && !readingAhead {
+				subdirectory = f.directoryReader.read(filepath.Join(listing.path, entry.name))
+			}
+			readAhead()
+			if err == nil {
+				err = f.walkListing(entryPath, entry.info, subdirectory, channel)
+			}
+		default:
+			err = f.processWalkEntry(entryPath, entry.info, nil, channel)
+		}
+		if err != nil && !errors.Is(err, filepath.SkipDir) {
+			return err
+		}
+	}
+	return nil
+}
+
+// mayEnterDirectory reports whether the directory described by info is
+// likely to be walked once reached, without logging or claiming it as
+// processWalkEntry does, so it can be read ahead.
+func (f *baseFinder) mayEnterDirectory(info os.FileInfo) bool {
+	device, inode, ok := fileIdentity(info)
+	if !ok {
+		return true
+	}
+	if _, excluded := f.excludedDevices[device]; excluded {
+		return false
+	}
+	if f.oneFileSystem && f.rootDeviceKnown && device != f.rootDevice {
+		return false
+	}
+	return f.symlinks != FollowSymlinks || !f.visitedDirectories[inodeIdentity{device: device, inode: inode}]
+}
@@ -0,0 +1,62 @@
+package finder
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"fdups/hasher"
+)
+
+// walkedPaths returns the paths of the files yielded by walking root with
+// the given number of directory workers, in the order they were yielded.
+func walkedPaths(t *testing.T, root string, workers int) []string {
+	t.Helper()
+	f := newBaseFinder(root, hasher.NewDefaultHasher, acceptAllFiles, []Option{WithDirectoryWorkers(workers)})
+	var paths []string
+	for item := range f.walker() {
+		if item.err != nil {
+			t.Fatalf("walk failed: %v", item.err)
+		}
+		paths = append(paths, item.fileInfo.Path)
+	}
+	return paths
+}
+
+func TestWithDirectoryWorkersKeepsLexicalOrder(t *testing.T) {
+	root := t.TempDir()
+	files := make(map[string]string)
+	// More subdirectories than workers, so reading ahead stops at the
+	// number of workers and resumes as directories are walked.
+	for i := 0; i < 12; i++ {
+		files[fmt.Sprintf("%02d/file", i)] = "content"
+		files[fmt.Sprintf("%02d/nested/%02d/file", i, i)] = "content"
+		files[fmt.Sprintf("%02d/z", i)] = "content"
+	}
+	files["a"] = "content"
+	files["00.file"] = "content"
+	writeTree(t, root, files)
+
+	var want []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.Type().IsRegular() {
+			want = append(want, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 2, 3, DefaultDirectoryWorkers} {
+		t.Run(fmt.Sprint(workers), func(t *testing.T) {
+			for run := 0; run < 5; run++ {
+				if got := walkedPaths(t, root, workers); !reflect.DeepEqual(got, want) {
+					t.Fatalf("walked %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package hasher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+func init() {
+	RegisterAudioDecoder(aiffDecoder{})
+}
+
+// aiffDecoder decodes uncompressed AIFF and AIFF-C files for the audio hasher.
+type aiffDecoder struct{}
+
+func (aiffDecoder) Detect(head []byte) bool {
+	if len(head) < 12 || !bytes.Equal(head[0:4], []byte("FORM")) {
+		return false
+	}
+	return bytes.Equal(head[8:12], []byte("AIFF")) || bytes.Equal(head[8:12], []byte("AIFC"))
+}
+
+func (aiffDecoder) Decode(r io.Reader, w PCMWriter) error {
+	var form [12]byte
+	if _, err := io.ReadFull(r, form[:]); err != nil {
+		return err
+	}
+	compressed := bytes.Equal(form[8:12], []byte("AIFC"))
+
+	var layout *sampleLayout
+	var channels int
+	for {
+		header, err := readChunkHeader(r, binary.BigEndian)
+		if err == io.EOF {
+			return errors.New("AIFF file has no SSND chunk")
+		}
+		if err != nil {
+			return err
+		}
+
+		switch header.id {
+		case "COMM":
+			format, parsed, err := parseAiffCommon(r, header.size, compressed)
+			if err != nil {
+				return err
+			}
+			if err := w.WriteFormat(format); err != nil {
+				return err
+			}
+			layout, channels = &parsed, int(format.Channels)
+		case "SSND":
+			if layout == nil {
+				return errors.New("AIFF SSND chunk precedes COMM chunk")
+			}
+			return decodeAiffSoundData(r, header.size, *layout, channels, w)
+		default:
+			if err := skipChunk(r, header.size); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseAiffCommon parses the body of an AIFF COMM chunk of the given size.
+func parseAiffCommon(r io.Reader, size uint32, compressed bool) (PCMFormat, sampleLayout, error) {
+	if size < 18 || (compressed && size < 22) {
+		return PCMFormat{}, sampleLayout{}, errors.New("AIFF COMM chunk too short")
+	}
+	body := make([]byte, int(size)+int(size&1))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return PCMFormat{}, sampleLayout{}, err
+	}
+
+	channels := binary.BigEndian.Uint16(body[0:])
+	bits := int(binary.BigEndian.Uint16(body[6:]))
+	var rate [10]byte
+	copy(rate[:], body[8:18])
+
+	bigEndian := true
+	if compressed {
+		switch compression := string(body[18:22]); compression {
+		case "NONE", "twos":
+		case "sowt":
+			bigEndian = false
+		default:
+			return PCMFormat{}, sampleLayout{}, fmt.Errorf("unsupported AIFF-C compression %q", compression)
+		}
+	}
+	if channels == 0 {
+		return PCMFormat{}, sampleLayout{}, errors.New("AIFF file has no channels")
+	}
+
+	layout, err := newSampleLayout(bits, bits, bigEndian, false)
+	if err != nil {
+		return PCMFormat{}, sampleLayout{}, err
+	}
+	format := PCMFormat{
+		SampleRate:    extendedToUint32(rate),
+		Channels:      channels,
+		BitsPerSample: uint16(bits),
+	}
+	return format, layout, nil
+}
+
+// decodeAiffSoundData decodes the body of an AIFF SSND chunk of the given size.
+func decodeAiffSoundData(r io.Reader, size uint32, layout sampleLayout, channels int, w PCMWriter) error {
+	var header [8]byte
+	if size < 8 {
+		return errors.New("AIFF SSND chunk too short")
+	}
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return err
+	}
+	offset := binary.BigEndian.Uint32(header[0:])
+	if offset > size-8 {
+		return errors.New("AIFF SSND offset exceeds chunk")
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(offset)); err != nil {
+		return err
+	}
+	return decodePCMData(r, int64(size-8-offset), layout, channels, w)
+}
@@ -0,0 +1,1000 @@
+package hasher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+)
+
+const (
+	// alacConfigSize is the size of the ALAC magic cookie.
+	alacConfigSize = 24
+	// alacMaxFrameLength bounds the number of samples per channel in a
+	// packet, so that corrupt cookies cannot trigger huge allocations.
+	alacMaxFrameLength = 1 << 16
+	// alacMaxChannels is the number of channels ALAC supports.
+	alacMaxChannels = 8
+	// alacMaxCoefficients is the largest predictor order a packet can use.
+	alacMaxCoefficients = 31
+)
+
+// ALAC element tags.
+const (
+	alacElementSCE = 0 // single channel element
+	alacElementCPE = 1 // channel pair element
+	alacElementCCE = 2 // coupling channel element
+	alacElementLFE = 3 // low frequency effects channel element
+	alacElementDSE = 4 // data stream element
+	alacElementPCE = 5 // program config element
+	alacElementFIL = 6 // fill element
+	alacElementEND = 7 // end of packet
+)
+
+// Parameters of the adaptive Golomb coding of ALAC residuals.
+const (
+	alacQBShift   = 9
+	alacQB        = 1 << alacQBShift
+	alacMMulShift = 2
+	alacMDenShift = alacQBShift - alacMMulShift - 1
+	alacMOff      = 1 << (alacMDenShift - 2)
+	alacBitOff    = 24
+	alacMaxPrefix = 9
+	alacRunBits   = 16
+	alacMaxRun    = 1<<alacRunBits - 1
+	alacMeanClamp = 0xffff
+)
+
+// alacChannelOffsets maps the first channel of each element, in ALAC
+// channel order, to the interleaved WAV channel order the decoder outputs,
+// by channel count.
+var alacChannelOffsets = [alacMaxChannels][alacMaxChannels]int{
+	{0},
+	{0, 1},
+	{2, 0, 1},
+	{2, 0, 1, 3},
+	{2, 0, 1, 3, 4},
+	{2, 0, 1, 4, 5, 3},
+	{2, 0, 1, 4, 5, 6, 3},
+	{2, 6, 7, 0, 1, 4, 5, 3},
+}
+
+func init() {
+	RegisterAudioDecoder(alacDecoder{})
+}
+
+// alacDecoder decodes Apple Lossless audio stored in MP4 files for the audio
+// hasher.
+//
+// The media data is streamed when the moov box precedes it. Otherwise the
+// sample tables are not known yet when the media data is read, and it is
+// buffered in memory until the moov box is found. Packets must be stored in
+// decoding order, as muxers do.
+type alacDecoder struct{}
+
+func (alacDecoder) Detect(head []byte) bool {
+	return len(head) >= 8 && bytes.Equal(head[4:8], []byte("ftyp"))
+}
+
+func (alacDecoder) Decode(r io.Reader, w PCMWriter) error {
+	counter := &countingReader{r: r}
+	reader := bufio.NewReader(counter)
+	offset := func() int64 { return counter.n - int64(reader.Buffered()) }
+
+	// pending holds the media data read before the moov box, by offset.
+	type mediaData struct {
+		offset int64
+		data   []byte
+	}
+	var pending []mediaData
+	var track *alacTrack
+
+	for {
+		boxType, payloadSize, err := readBoxHeader(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		start := offset()
+		open := payloadSize < 0
+		if open {
+			// The box extends to the end of the file.
+			payloadSize = math.MaxInt64 - start
+		}
+		payload := io.LimitReader(reader, payloadSize)
+
+		switch {
+		case boxType == "moov" && track == nil:
+			moov, err := io.ReadAll(payload)
+			if err != nil {
+				return err
+			}
+			if track, err = parseAlacTrack(moov); err != nil {
+				return err
+			}
+			if err := w.WriteFormat(track.format()); err != nil {
+				return err
+			}
+			for _, media := range pending {
+				if err := track.decodeMediaData(bytes.NewReader(media.data), media.offset, int64(len(media.data)), w); err != nil {
+					return err
+				}
+			}
+			pending = nil
+		case boxType == "mdat" && track == nil:
+			data, err := io.ReadAll(payload)
+			if err != nil {
+				return err
+			}
+			pending = append(pending, mediaData{start, data})
+		case boxType == "mdat":
+			if err := track.decodeMediaData(payload, start, payloadSize, w); err != nil {
+				return err
+			}
+		}
+		if _, err := io.Copy(io.Discard, payload); err != nil {
+			return err
+		}
+		if !open && offset()-start < payloadSize {
+			return fmt.Errorf("truncated %q box: %w", boxType, io.ErrUnexpectedEOF)
+		}
+	}
+
+	if track == nil {
+		return errors.New("no moov box found")
+	}
+	if remaining := track.table.remaining(); remaining > 0 {
+		return fmt.Errorf("media data ends %d ALAC packets early: %w", remaining, io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// nextBox splits the first box off data, returning its type and payload
+// along with the boxes following it.
+func nextBox(data []byte) (boxType string, payload, rest []byte, err error) {
+	if len(data) < boxHeaderSize {
+		return "", nil, nil, errors.New("truncated box header")
+	}
+	size := uint64(binary.BigEndian.Uint32(data))
+	boxType = string(data[4:8])
+	headerSize := uint64(boxHeaderSize)
+	switch size {
+	case 0:
+		size = uint64(len(data))
+	case 1:
+		if len(data) < boxHeaderSize+boxLargeSizeLength {
+			return "", nil, nil, fmt.Errorf("truncated %q box header", boxType)
+		}
+		size = binary.BigEndian.Uint64(data[boxHeaderSize:])
+		headerSize += boxLargeSizeLength
+	}
+	if size < headerSize || size > uint64(len(data)) {
+		return "", nil, nil, fmt.Errorf("invalid size %d of %q box", size, boxType)
+	}
+	return boxType, data[headerSize:size], data[size:], nil
+}
+
+// findBox returns the payload of the first box of the given type in data,
+// or nil if there is none.
+func findBox(data []byte, boxType string) ([]byte, error) {
+	for len(data) > 0 {
+		found, payload, rest, err := nextBox(data)
+		if err != nil {
+			return nil, err
+		}
+		if found == boxType {
+			return payload, nil
+		}
+		data = rest
+	}
+	return nil, nil
+}
+
+// alacConfig is the ALAC magic cookie, ALACSpecificConfig in Apple's
+// reference implementation.
+type alacConfig struct {
+	frameLength uint32
+	bitDepth    int
+	pb          uint32
+	mb          uint32
+	kb          uint32
+	channels    int
+	sampleRate  uint32
+}
+
+// parseAlacConfig parses and validates a magic cookie.
+func parseAlacConfig(cookie []byte) (alacConfig, error) {
+	if len(cookie) < alacConfigSize {
+		return alacConfig{}, errors.New("ALAC magic cookie too short")
+	}
+	config := alacConfig{
+		frameLength: binary.BigEndian.Uint32(cookie[0:]),
+		bitDepth:    int(cookie[5]),
+		pb:          uint32(cookie[6]),
+		mb:          uint32(cookie[7]),
+		kb:          uint32(cookie[8]),
+		channels:    int(cookie[9]),
+		sampleRate:  binary.BigEndian.Uint32(cookie[20:]),
+	}
+	switch {
+	case cookie[4] != 0:
+		return alacConfig{}, fmt.Errorf("unsupported ALAC version %d", cookie[4])
+	case config.frameLength == 0 || config.frameLength > alacMaxFrameLength:
+		return alacConfig{}, fmt.Errorf("unsupported ALAC frame length %d", config.frameLength)
+	case config.bitDepth != 16 && config.bitDepth != 20 && config.bitDepth != 24 && config.bitDepth != 32:
+		return alacConfig{}, fmt.Errorf("unsupported ALAC bit depth %d", config.bitDepth)
+	case config.channels == 0 || config.channels > alacMaxChannels:
+		return alacConfig{}, fmt.Errorf("unsupported ALAC channel count %d", config.channels)
+	case config.kb == 0 || config.kb > 31:
+		return alacConfig{}, fmt.Errorf("invalid ALAC rice limit %d", config.kb)
+	}
+	return config, nil
+}
+
+// alacTrack is an ALAC audio track with its sample tables.
+type alacTrack struct {
+	config  alacConfig
+	table   *sampleTable
+	decoder *alacPacketDecoder
+}
+
+// parseAlacTrack finds the first ALAC track in the payload of a moov box.
+func parseAlacTrack(moov []byte) (*alacTrack, error) {
+	for len(moov) > 0 {
+		boxType, trak, rest, err := nextBox(moov)
+		if err != nil {
+			return nil, err
+		}
+		moov = rest
+		if boxType != "trak" {
+			continue
+		}
+
+		stbl := trak
+		for _, boxType := range []string{"mdia", "minf", "stbl"} {
+			if stbl, err = findBox(stbl, boxType); err != nil || stbl == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if stbl == nil {
+			continue
+		}
+		cookie, err := findAlacCookie(stbl)
+		if err != nil {
+			return nil, err
+		}
+		if cookie == nil {
+			continue
+		}
+
+		config, err := parseAlacConfig(cookie)
+		if err != nil {
+			return nil, err
+		}
+		table, err := parseSampleTable(stbl)
+		if err != nil {
+			return nil, err
+		}
+		return &alacTrack{config: config, table: table, decoder: newAlacPacketDecoder(config)}, nil
+	}
+	return nil, errors.New("no ALAC track found")
+}
+
+// findAlacCookie returns the magic cookie of the ALAC sample entry in the
+// stsd box of stbl, or nil if the track is not ALAC.
+func findAlacCookie(stbl []byte) ([]byte, error) {
+	stsd, err := findBox(stbl, "stsd")
+	if err != nil || stsd == nil {
+		return nil, err
+	}
+	if len(stsd) < 8 {
+		return nil, errors.New("stsd box too short")
+	}
+	boxType, entry, _, err := nextBox(stsd[8:])
+	if err != nil || boxType != "alac" {
+		return nil, err
+	}
+
+	// The sound sample entry is 28 bytes long, plus 16 bytes in version 1
+	// and 36 bytes in version 2 of the QuickTime layout.
+	if len(entry) < 28 {
+		return nil, errors.New("ALAC sample entry too short")
+	}
+	childrenStart := 28
+	switch binary.BigEndian.Uint16(entry[8:]) {
+	case 1:
+		childrenStart += 16
+	case 2:
+		childrenStart += 36
+	}
+	if len(entry) < childrenStart {
+		return nil, errors.New("ALAC sample entry too short")
+	}
+	children := entry[childrenStart:]
+
+	// QuickTime files may wrap the cookie in a wave box.
+	if wave, err := findBox(children, "wave"); err != nil {
+		return nil, err
+	} else if wave != nil {
+		children = wave
+	}
+	cookie, err := findBox(children, "alac")
+	if err != nil {
+		return nil, err
+	}
+	if len(cookie) < 4+alacConfigSize {
+		return nil, errors.New("ALAC sample entry has no magic cookie")
+	}
+	// Skip the version and flags of the full box.
+	return cookie[4:], nil
+}
+
+// format returns the PCM format the track decodes to.
+func (t *alacTrack) format() PCMFormat {
+	return PCMFormat{
+		SampleRate:    t.config.sampleRate,
+		Channels:      uint16(t.config.channels),
+		BitsPerSample: uint16(t.config.bitDepth),
+	}
+}
+
+// maxPacketSize bounds the size of a packet, which never exceeds the size
+// of its samples stored uncompressed by much.
+func (t *alacTrack) maxPacketSize() uint32 {
+	return t.config.frameLength*uint32(t.config.channels)*4 + 1024
+}
+
+// decodeMediaData decodes the packets stored in the size bytes of media
+// data read from r, which start at offset in the file.
+func (t *alacTrack) decodeMediaData(r io.Reader, offset, size int64, w PCMWriter) error {
+	position := offset
+	end := offset + size
+	var packet []byte
+	for {
+		packetOffset, packetSize, ok := t.table.peek()
+		if !ok || packetOffset >= end {
+			return nil
+		}
+		if packetOffset < position {
+			return errors.New("ALAC packets are not stored in decoding order")
+		}
+		if packetSize > t.maxPacketSize() || packetOffset+int64(packetSize) > end {
+			return fmt.Errorf("invalid size %d of ALAC packet", packetSize)
+		}
+
+		if _, err := io.CopyN(io.Discard, r, packetOffset-position); err != nil {
+			return unexpectedEOF(err)
+		}
+		packet = resize(packet, int(packetSize))
+		if _, err := io.ReadFull(r, packet); err != nil {
+			return unexpectedEOF(err)
+		}
+		position = packetOffset + int64(packetSize)
+		if err := t.table.advance(); err != nil {
+			return err
+		}
+
+		samples, err := t.decoder.decode(packet)
+		if err != nil {
+			return err
+		}
+		if err := w.WriteSamples(samples); err != nil {
+			return err
+		}
+	}
+}
+
+// sampleTable enumerates the file offset and size of the samples of a
+// track, in decoding order, from its stsz, stsc and stco or co64 boxes.
+type sampleTable struct {
+	sizes      []byte // sample sizes of stsz, or nil if fixedSize is used
+	fixedSize  uint32
+	count      uint32
+	chunks     []byte // stsc entries
+	offsets    []byte // chunk offsets of stco or co64
+	offsetSize int
+
+	sample        uint32 // index of the next sample
+	chunk         uint32 // index of the chunk holding the next sample
+	chunkSamples  uint32 // number of samples in the chunk
+	sampleInChunk uint32 // index of the next sample in its chunk
+	offset        int64  // offset of the next sample
+}
+
+// parseSampleTable parses the sample tables of stbl.
+func parseSampleTable(stbl []byte) (*sampleTable, error) {
+	stsz, err := findBox(stbl, "stsz")
+	if err != nil {
+		return nil, err
+	}
+	stsc, err := findBox(stbl, "stsc")
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := findBox(stbl, "stco")
+	offsetSize := 4
+	if err == nil && offsets == nil {
+		offsets, err = findBox(stbl, "co64")
+		offsetSize = 8
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(stsz) < 12 || len(stsc) < 8 || len(offsets) < 8 {
+		return nil, errors.New("ALAC track has no sample tables")
+	}
+
+	t := &sampleTable{
+		fixedSize:  binary.BigEndian.Uint32(stsz[4:]),
+		count:      binary.BigEndian.Uint32(stsz[8:]),
+		chunks:     stsc[8:],
+		offsets:    offsets[8:],
+		offsetSize: offsetSize,
+	}
+	if t.fixedSize == 0 {
+		t.sizes = stsz[12:]
+		if uint64(len(t.sizes)) < uint64(t.count)*4 {
+			return nil, errors.New("stsz box too short")
+		}
+	}
+	chunkEntries := binary.BigEndian.Uint32(stsc[4:])
+	if uint64(len(t.chunks)) < uint64(chunkEntries)*12 {
+		return nil, errors.New("stsc box too short")
+	}
+	t.chunks = t.chunks[:chunkEntries*12]
+	chunkCount := binary.BigEndian.Uint32(offsets[4:])
+	if uint64(len(t.offsets)) < uint64(chunkCount)*uint64(offsetSize) {
+		return nil, fmt.Errorf("chunk offset box too short")
+	}
+	t.offsets = t.offsets[:int(chunkCount)*offsetSize]
+	if err := t.startChunk(0); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// startChunk makes the chunk with the given index hold the next sample.
+func (t *sampleTable) startChunk(chunk uint32) error {
+	if t.sample >= t.count {
+		return nil
+	}
+	if int(chunk) >= len(t.offsets)/t.offsetSize {
+		return errors.New("ALAC samples exceed the chunk offset table")
+	}
+
+	// The last stsc entry whose first chunk, counted from 1, is not after
+	// this one gives the number of samples per chunk.
+	t.chunkSamples = 0
+	for entry := t.chunks; len(entry) >= 12; entry = entry[12:] {
+		if binary.BigEndian.Uint32(entry) > chunk+1 {
+			break
+		}
+		t.chunkSamples = binary.BigEndian.Uint32(entry[4:])
+	}
+	if t.chunkSamples == 0 {
+		return errors.New("ALAC chunk holds no samples")
+	}
+
+	t.chunk = chunk
+	t.sampleInChunk = 0
+	if t.offsetSize == 8 {
+		offset := binary.BigEndian.Uint64(t.offsets[chunk*8:])
+		if offset > math.MaxInt64/2 {
+			return fmt.Errorf("invalid ALAC chunk offset %d", offset)
+		}
+		t.offset = int64(offset)
+	} else {
+		t.offset = int64(binary.BigEndian.Uint32(t.offsets[chunk*4:]))
+	}
+	return nil
+}
+
+// peek returns the offset and size of the next sample, or false if all
+// samples have been enumerated.
+func (t *sampleTable) peek() (int64, uint32, bool) {
+	if t.sample >= t.count {
+		return 0, 0, false
+	}
+	size := t.fixedSize
+	if t.sizes != nil {
+		size = binary.BigEndian.Uint32(t.sizes[t.sample*4:])
+	}
+	return t.offset, size, true
+}
+
+// advance moves on to the next sample.
+func (t *sampleTable) advance() error {
+	_, size, ok := t.peek()
+	if !ok {
+		return nil
+	}
+	t.sample++
+	t.sampleInChunk++
+	t.offset += int64(size)
+	if t.sampleInChunk == t.chunkSamples {
+		return t.startChunk(t.chunk + 1)
+	}
+	return nil
+}
+
+// remaining returns the number of samples not enumerated yet.
+func (t *sampleTable) remaining() uint32 {
+	return t.count - t.sample
+}
+
+// alacPacketDecoder decodes ALAC packets into interleaved samples.
+type alacPacketDecoder struct {
+	config     alacConfig
+	residuals  []int32
+	mix        [2][]int32
+	shift      [2][]uint32
+	coefs      [2][alacMaxCoefficients]int16
+	interleave []int32
+}
+
+func newAlacPacketDecoder(config alacConfig) *alacPacketDecoder {
+	d := &alacPacketDecoder{
+		config:    config,
+		residuals: make([]int32, config.frameLength),
+	}
+	for channel := range d.mix {
+		d.mix[channel] = make([]int32, config.frameLength)
+		d.shift[channel] = make([]uint32, config.frameLength)
+	}
+	return d
+}
+
+// decode decodes a packet and returns its interleaved samples, which stay
+// valid until the next call.
+func (d *alacPacketDecoder) decode(packet []byte) ([]int32, error) {
+	r := &alacBitReader{data: packet}
+	channels := d.config.channels
+	channel := 0
+	frameSamples := -1
+
+	for channel < channels {
+		tag, err := r.read(3)
+		if err != nil {
+			return nil, err
+		}
+
+		switch tag {
+		case alacElementSCE, alacElementLFE, alacElementCPE:
+			pair := tag == alacElementCPE
+			width := 1
+			if pair {
+				width = 2
+			}
+			output := alacChannelOffsets[channels-1][channel]
+			if channel+width > channels || output+width > channels {
+				return nil, errors.New("ALAC packet holds more channels than configured")
+			}
+			samples, err := d.decodeElement(r, pair)
+			if err != nil {
+				return nil, err
+			}
+			if frameSamples < 0 {
+				frameSamples = samples
+				d.interleave = resize(d.interleave, samples*channels)
+			} else if samples != frameSamples {
+				return nil, errors.New("ALAC elements differ in length")
+			}
+			for i := range width {
+				for j, sample := range d.mix[i][:samples] {
+					d.interleave[j*channels+output+i] = sample
+				}
+			}
+			channel += width
+		case alacElementDSE:
+			if err := r.skipDataStream(); err != nil {
+				return nil, err
+			}
+		case alacElementFIL:
+			if err := r.skipFill(); err != nil {
+				return nil, err
+			}
+		case alacElementEND:
+			return nil, fmt.Errorf("ALAC packet ends after %d of %d channels", channel, channels)
+		default:
+			return nil, fmt.Errorf("unsupported ALAC element %d", tag)
+		}
+	}
+	return d.interleave, nil
+}
+
+// decodeElement decodes a single channel or channel pair element into the
+// mix buffers, returning the number of samples per channel.
+func (d *alacPacketDecoder) decodeElement(r *alacBitReader, pair bool) (int, error) {
+	// Skip the element instance tag.
+	if err := r.skip(4); err != nil {
+		return 0, err
+	}
+	unused, err := r.read(12)
+	if err != nil {
+		return 0, err
+	}
+	header, err := r.read(4)
+	if err != nil {
+		return 0, err
+	}
+	if unused != 0 {
+		return 0, errors.New("invalid ALAC element header")
+	}
+	partial := header&8 != 0
+	bytesShifted := int(header>>1) & 3
+	escape := header&1 != 0
+	if bytesShifted == 3 {
+		return 0, errors.New("invalid ALAC shift")
+	}
+
+	samples := int(d.config.frameLength)
+	if partial {
+		count, err := r.read(32)
+		if err != nil {
+			return 0, err
+		}
+		if count == 0 || count > d.config.frameLength {
+			return 0, fmt.Errorf("invalid ALAC packet length %d", count)
+		}
+		samples = int(count)
+	}
+	channels := 1
+	if pair {
+		channels = 2
+	}
+
+	if escape {
+		// Uncompressed samples are interleaved.
+		for i := range samples {
+			for channel := range channels {
+				value, err := r.read(d.config.bitDepth)
+				if err != nil {
+					return 0, err
+				}
+				d.mix[channel][i] = signExtend(value, d.config.bitDepth)
+			}
+		}
+		return samples, nil
+	}
+
+	chanBits := d.config.bitDepth - 8*bytesShifted + channels - 1
+	if chanBits > 32 {
+		return 0, fmt.Errorf("unsupported ALAC sample size of %d bits", chanBits)
+	}
+	mixBits, err := r.read(8)
+	if err != nil {
+		return 0, err
+	}
+	mixRes, err := r.read(8)
+	if err != nil {
+		return 0, err
+	}
+
+	var params [2]struct {
+		mode, denShift, pbFactor uint32
+		coefs                    []int16
+	}
+	for channel := range channels {
+		p := &params[channel]
+		header, err := r.read(16)
+		if err != nil {
+			return 0, err
+		}
+		p.mode, p.denShift = header>>12, header>>8&15
+		p.pbFactor = header >> 5 & 7
+		p.coefs = d.coefs[channel][:header&31]
+		for i := range p.coefs {
+			coef, err := r.read(16)
+			if err != nil {
+				return 0, err
+			}
+			p.coefs[i] = int16(coef)
+		}
+	}
+
+	// The shift buffer precedes the residuals but is applied last.
+	shiftBits := 8 * bytesShifted
+	shiftReader := *r
+	if err := r.skip(shiftBits * channels * samples); err != nil {
+		return 0, err
+	}
+
+	for channel := range channels {
+		p := &params[channel]
+		residuals := d.residuals[:samples]
+		pb := d.config.pb * p.pbFactor / 4
+		if err := r.readResiduals(residuals, d.config.mb, pb, d.config.kb, chanBits); err != nil {
+			return 0, err
+		}
+		if p.mode != 0 {
+			unpredict(residuals, residuals, nil, alacMaxCoefficients, chanBits, 0)
+		}
+		unpredict(residuals, d.mix[channel][:samples], p.coefs, len(p.coefs), chanBits, uint(p.denShift))
+	}
+
+	if pair && mixRes != 0 {
+		u, v := d.mix[0][:samples], d.mix[1][:samples]
+		for i := range samples {
+			left := u[i] + v[i] - (int32(int8(mixRes))*v[i])>>mixBits
+			u[i], v[i] = left, left-v[i]
+		}
+	}
+
+	if shiftBits > 0 {
+		for i := range samples {
+			for channel := range channels {
+				low, err := shiftReader.read(shiftBits)
+				if err != nil {
+					return 0, err
+				}
+				d.mix[channel][i] = d.mix[channel][i]<<shiftBits | int32(low)
+			}
+		}
+	}
+	return samples, nil
+}
+
+// unpredict reverses the adaptive FIR prediction of ALAC with the given
+// coefficients, which it adapts, writing the samples of chanBits bits
+// predicted from residuals to out. A numActive of 31 selects first-order
+// prediction, which can run in place.
+func unpredict(residuals, out []int32, coefs []int16, numActive, chanBits int, denShift uint) {
+	if len(residuals) == 0 {
+		return
+	}
+	chanShift := 32 - chanBits
+	wrap := func(value int32) int32 { return value << chanShift >> chanShift }
+
+	out[0] = residuals[0]
+	switch {
+	case numActive == 0:
+		copy(out, residuals)
+		return
+	case numActive == alacMaxCoefficients:
+		for j := 1; j < len(residuals); j++ {
+			out[j] = wrap(residuals[j] + out[j-1])
+		}
+		return
+	}
+
+	for j := 1; j <= numActive && j < len(residuals); j++ {
+		out[j] = wrap(residuals[j] + out[j-1])
+	}
+	denHalf := int32(1) << denShift >> 1
+	for j := numActive + 1; j < len(residuals); j++ {
+		top := out[j-numActive-1]
+		var sum int32
+		for k := range numActive {
+			sum += int32(coefs[k]) * (out[j-1-k] - top)
+		}
+
+		residual := residuals[j]
+		out[j] = wrap(residual + top + (sum+denHalf)>>denShift)
+
+		// Adapt the coefficients towards the sign of the residual.
+		sign := signOf(residual)
+		for k := numActive - 1; k >= 0 && sign != 0; k-- {
+			difference := top - out[j-1-k]
+			differenceSign := signOf(difference)
+			if sign > 0 {
+				coefs[k] -= int16(differenceSign)
+				residual -= int32(numActive-k) * (differenceSign * difference >> denShift)
+				if residual <= 0 {
+					break
+				}
+			} else {
+				coefs[k] += int16(differenceSign)
+				residual -= int32(numActive-k) * (-differenceSign * difference >> denShift)
+				if residual >= 0 {
+					break
+				}
+			}
+		}
+	}
+}
+
+// resize returns buffer with its length set to n, reallocating it only if
+// its capacity is too small. The contents are not preserved.
+func resize[T any](buffer []T, n int) []T {
+	if cap(buffer) < n {
+		return make([]T, n)
+	}
+	return buffer[:n]
+}
+
+// signOf returns -1, 0 or 1 depending on the sign of value.
+func signOf(value int32) int32 {
+	switch {
+	case value > 0:
+		return 1
+	case value < 0:
+		return -1
+	}
+	return 0
+}
+
+// signExtend returns the value of the low bits of value as a signed integer.
+func signExtend(value uint32, bits int) int32 {
+	return int32(value<<(32-bits)) >> (32 - bits)
+}
+
+// alacBitReader reads big-endian bit fields from an ALAC packet.
+type alacBitReader struct {
+	data     []byte
+	position int
+}
+
+var errAlacPacketTruncated = fmt.Errorf("truncated ALAC packet: %w", io.ErrUnexpectedEOF)
+
+// read returns the next n bits, where n is at most 32.
+func (r *alacBitReader) read(n int) (uint32, error) {
+	if r.position+n > len(r.data)*8 {
+		return 0, errAlacPacketTruncated
+	}
+	var value uint64
+	for n > 0 {
+		offset := r.position & 7
+		taken := min(8-offset, n)
+		bits := uint64(r.data[r.position>>3]>>(8-offset-taken)) & (1<<taken - 1)
+		value = value<<taken | bits
+		r.position += taken
+		n -= taken
+	}
+	return uint32(value), nil
+}
+
+// skip skips the next n bits.
+func (r *alacBitReader) skip(n int) error {
+	if r.position+n > len(r.data)*8 {
+		return errAlacPacketTruncated
+	}
+	r.position += n
+	return nil
+}
+
+// align skips to the next byte boundary.
+func (r *alacBitReader) align() {
+	r.position = (r.position + 7) &^ 7
+}
+
+// skipDataStream skips the body of a data stream element.
+func (r *alacBitReader) skipDataStream() error {
+	// Skip the element instance tag.
+	if err := r.skip(4); err != nil {
+		return err
+	}
+	aligned, err := r.read(1)
+	if err != nil {
+		return err
+	}
+	count, err := r.read(8)
+	if err != nil {
+		return err
+	}
+	if count == 255 {
+		extra, err := r.read(8)
+		if err != nil {
+			return err
+		}
+		count += extra
+	}
+	if aligned != 0 {
+		r.align()
+	}
+	return r.skip(int(count) * 8)
+}
+
+// skipFill skips the body of a fill element.
+func (r *alacBitReader) skipFill() error {
+	count, err := r.read(4)
+	if err != nil {
+		return err
+	}
+	if count == 15 {
+		extra, err := r.read(8)
+		if err != nil {
+			return err
+		}
+		count += extra - 1
+	}
+	return r.skip(int(count) * 8)
+}
+
+// readResiduals decodes the adaptive Golomb coded residuals of a channel
+// into residuals, escaping values to chanBits bits.
+func (r *alacBitReader) readResiduals(residuals []int32, mb, pb, kb uint32, chanBits int) error {
+	wb := uint32(1)<<kb - 1
+	zeroMode := uint32(0)
+	for c := 0; c < len(residuals); {
+		k := min(uint32(31-bits.LeadingZeros32(mb>>alacQBShift+3)), kb)
+		n, err := r.readGolomb(uint32(1)<<k-1, k, chanBits)
+		if err != nil {
+			return err
+		}
+
+		// The least significant bit holds the sign.
+		value := int32(n + zeroMode)
+		sign := -(value & 1) | 1
+		residuals[c] = ((value + 1) >> 1) * sign
+		c++
+
+		mb = pb*(n+zeroMode) + mb - (pb*mb)>>alacQBShift
+		if n > alacMeanClamp {
+			mb = alacMeanClamp
+		}
+		zeroMode = 0
+
+		if mb<<alacMMulShift < alacQB && c < len(residuals) {
+			// A run of zeros follows.
+			zeroMode = 1
+			k := uint32(bits.LeadingZeros32(mb)) - alacBitOff + (mb+alacMOff)>>alacMDenShift
+			run, err := r.readGolomb((uint32(1)<<k-1)&wb, k, alacRunBits)
+			if err != nil {
+				return err
+			}
+			if uint64(c)+uint64(run) > uint64(len(residuals)) {
+				return errors.New("ALAC zero run exceeds packet")
+			}
+			clear(residuals[c : c+int(run)])
+			c += int(run)
+			if run >= alacMaxRun {
+				zeroMode = 0
+			}
+			mb = 0
+		}
+	}
+	return nil
+}
+
+// readGolomb reads a value coded with the ALAC variant of Golomb coding
+// with divisor m = 2^k-1, escaped to escapeBits bits after alacMaxPrefix
+// ones.
+func (r *alacBitReader) readGolomb(m, k uint32, escapeBits int) (uint32, error) {
+	prefix := uint32(0)
+	for prefix < alacMaxPrefix {
+		bit, err := r.read(1)
+		if err != nil {
+			return 0, err
+		}
+		if bit == 0 {
+			break
+		}
+		prefix++
+	}
+	if prefix == alacMaxPrefix {
+		return r.read(escapeBits)
+	}
+
+	// Remainders of zero are stored in k-1 bits, the others in k bits as
+	// remainder+1.
+	if r.position+int(k) > len(r.data)*8 {
+		return 0, errAlacPacketTruncated
+	}
+	v, _ := r.read(int(k))
+	if v < 2 {
+		r.position--
+		return prefix * m, nil
+	}
+	return prefix*m + v - 1, nil
+}
@@ -0,0 +1,419 @@
+package hasher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+	"testing"
+)
+
+// alacBitWriter writes big-endian bit fields.
+type alacBitWriter struct {
+	data []byte
+	bits int
+}
+
+func (w *alacBitWriter) write(value uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.bits%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		if value>>i&1 != 0 {
+			w.data[len(w.data)-1] |= 0x80 >> (w.bits % 8)
+		}
+		w.bits++
+	}
+}
+
+// writeGolomb mirrors alacBitReader.readGolomb.
+func (w *alacBitWriter) writeGolomb(n, m, k uint32, escapeBits int) {
+	prefix := n / m
+	if prefix >= alacMaxPrefix {
+		w.write(1<<alacMaxPrefix-1, alacMaxPrefix)
+		w.write(n, escapeBits)
+		return
+	}
+	w.write(1<<prefix-1, int(prefix))
+	w.write(0, 1)
+	if remainder := n % m; remainder == 0 {
+		w.write(0, int(k)-1)
+	} else {
+		w.write(remainder+1, int(k))
+	}
+}
+
+// writeResiduals mirrors alacBitReader.readResiduals.
+func (w *alacBitWriter) writeResiduals(residuals []int32, mb, pb, kb uint32, chanBits int) {
+	wb := uint32(1)<<kb - 1
+	zeroMode := uint32(0)
+	for c := 0; c < len(residuals); {
+		k := min(uint32(31-bits.LeadingZeros32(mb>>alacQBShift+3)), kb)
+		value := residuals[c]
+		coded := uint32(value) << 1
+		if value < 0 {
+			coded = uint32(-value)<<1 - 1
+		}
+		n := coded - zeroMode
+		w.writeGolomb(n, uint32(1)<<k-1, k, chanBits)
+		c++
+
+		mb = pb*(n+zeroMode) + mb - (pb*mb)>>alacQBShift
+		if n > alacMeanClamp {
+			mb = alacMeanClamp
+		}
+		zeroMode = 0
+
+		if mb<<alacMMulShift < alacQB && c < len(residuals) {
+			zeroMode = 1
+			k := uint32(bits.LeadingZeros32(mb)) - alacBitOff + (mb+alacMOff)>>alacMDenShift
+			run := uint32(0)
+			for c < len(residuals) && residuals[c] == 0 && run < alacMaxRun {
+				run++
+				c++
+			}
+			w.writeGolomb(run, (uint32(1)<<k-1)&wb, k, alacRunBits)
+			if run >= alacMaxRun {
+				zeroMode = 0
+			}
+			mb = 0
+		}
+	}
+}
+
+// predict mirrors unpredict, returning the residuals of samples.
+func predict(samples []int32, coefs []int16, chanBits int, denShift uint) []int32 {
+	chanShift := 32 - chanBits
+	wrap := func(value int32) int32 { return value << chanShift >> chanShift }
+	numActive := len(coefs)
+	residuals := make([]int32, len(samples))
+	if numActive == 0 {
+		copy(residuals, samples)
+		return residuals
+	}
+	residuals[0] = samples[0]
+	for j := 1; j <= numActive && j < len(samples); j++ {
+		residuals[j] = wrap(samples[j] - samples[j-1])
+	}
+
+	denHalf := int32(1) << denShift >> 1
+	for j := numActive + 1; j < len(samples); j++ {
+		top := samples[j-numActive-1]
+		var sum int32
+		for k := range numActive {
+			sum += int32(coefs[k]) * (samples[j-1-k] - top)
+		}
+		residual := wrap(samples[j] - top - (sum+denHalf)>>denShift)
+		residuals[j] = residual
+
+		sign := signOf(residual)
+		for k := numActive - 1; k >= 0 && sign != 0; k-- {
+			difference := top - samples[j-1-k]
+			differenceSign := signOf(difference)
+			if sign > 0 {
+				coefs[k] -= int16(differenceSign)
+				residual -= int32(numActive-k) * (differenceSign * difference >> denShift)
+				if residual <= 0 {
+					break
+				}
+			} else {
+				coefs[k] += int16(differenceSign)
+				residual -= int32(numActive-k) * (-differenceSign * difference >> denShift)
+				if residual >= 0 {
+					break
+				}
+			}
+		}
+	}
+	return residuals
+}
+
+// alacEncoding selects how alacPacket encodes the elements of a packet.
+type alacEncoding struct {
+	escape       bool
+	firstOrder   bool
+	coefs        []int16
+	denShift     uint
+	mixBits      uint32
+	mixRes       int8
+	bytesShifted int
+}
+
+// alacElementWidths lists the channels of the elements of a packet, by
+// channel count.
+var alacElementWidths = [][]int{{1}, {2}, {1, 2}}
+
+// alacPacket encodes the samples of each channel, in WAV channel order, as
+// an ALAC packet.
+func alacPacket(config alacConfig, channels [][]int32, encoding alacEncoding) []byte {
+	w := &alacBitWriter{}
+	samples := len(channels[0])
+	channel := 0
+	for _, width := range alacElementWidths[len(channels)-1] {
+		output := alacChannelOffsets[len(channels)-1][channel]
+		element := channels[output : output+width]
+		channel += width
+
+		w.write(uint32(width-1), 3) // SCE or CPE
+		w.write(0, 4+12)
+		partial := samples != int(config.frameLength)
+		header := uint32(encoding.bytesShifted) << 1
+		if partial {
+			header |= 8
+		}
+		if encoding.escape {
+			header = header&8 | 1
+		}
+		w.write(header, 4)
+		if partial {
+			w.write(uint32(samples), 32)
+		}
+
+		if encoding.escape {
+			for i := range samples {
+				for _, samples := range element {
+					w.write(uint32(samples[i]), config.bitDepth)
+				}
+			}
+			continue
+		}
+		alacElement(w, config, element, encoding)
+	}
+	w.write(alacElementEND, 3)
+	return w.data
+}
+
+// alacElement writes the body of a compressed element holding channels.
+func alacElement(w *alacBitWriter, config alacConfig, channels [][]int32, encoding alacEncoding) {
+	samples := len(channels[0])
+	shiftBits := 8 * encoding.bytesShifted
+	mix := make([][]int32, len(channels))
+	for channel, values := range channels {
+		mix[channel] = make([]int32, samples)
+		for i, value := range values {
+			mix[channel][i] = value >> shiftBits
+		}
+	}
+	if len(channels) == 2 && encoding.mixRes != 0 {
+		for i := range samples {
+			left, right := mix[0][i], mix[1][i]
+			v := left - right
+			mix[0][i], mix[1][i] = right+(int32(encoding.mixRes)*v)>>encoding.mixBits, v
+		}
+	}
+	chanBits := config.bitDepth - shiftBits + len(channels) - 1
+
+	w.write(encoding.mixBits, 8)
+	w.write(uint32(uint8(encoding.mixRes)), 8)
+	mode := uint32(0)
+	if encoding.firstOrder {
+		mode = 1
+	}
+	const pbFactor = 4
+	for range channels {
+		w.write(mode<<4|uint32(encoding.denShift), 8)
+		w.write(pbFactor<<5|uint32(len(encoding.coefs)), 8)
+		for _, coef := range encoding.coefs {
+			w.write(uint32(uint16(coef)), 16)
+		}
+	}
+	for i := range samples {
+		for _, values := range channels {
+			w.write(uint32(values[i])&(1<<shiftBits-1), shiftBits)
+		}
+	}
+	for _, values := range mix {
+		residuals := predict(values, append([]int16(nil), encoding.coefs...), chanBits, encoding.denShift)
+		if encoding.firstOrder {
+			chanShift := 32 - chanBits
+			for j := len(residuals) - 1; j > 0; j-- {
+				residuals[j] = (residuals[j] - residuals[j-1]) << chanShift >> chanShift
+			}
+		}
+		w.writeResiduals(residuals, config.mb, config.pb*pbFactor/4, config.kb, chanBits)
+	}
+}
+
+// alacFile returns an MP4 file holding the interleaved samples as an ALAC
+// track, encoded in packets of config.frameLength samples per channel and
+// stored in chunks of chunkPackets packets. The moov box follows the media
+// data if moovLast is set.
+func alacFile(config alacConfig, samples []int32, encoding alacEncoding, chunkPackets int, moovLast bool) []byte {
+	var packets [][]byte
+	frameSamples := int(config.frameLength) * config.channels
+	for start := 0; start < len(samples); start += frameSamples {
+		frame := samples[start:min(start+frameSamples, len(samples))]
+		channels := make([][]int32, config.channels)
+		for i, sample := range frame {
+			channels[i%config.channels] = append(channels[i%config.channels], sample)
+		}
+		packets = append(packets, alacPacket(config, channels, encoding))
+	}
+
+	cookie := binary.BigEndian.AppendUint32(nil, config.frameLength)
+	cookie = append(cookie, 0, byte(config.bitDepth), byte(config.pb), byte(config.mb), byte(config.kb), byte(config.channels))
+	cookie = append(cookie, make([]byte, 10)...)
+	cookie = binary.BigEndian.AppendUint32(cookie, config.sampleRate)
+	entry := make([]byte, 28)
+	binary.BigEndian.PutUint16(entry[6:], 1)
+	binary.BigEndian.PutUint16(entry[16:], uint16(config.channels))
+	binary.BigEndian.PutUint16(entry[18:], uint16(config.bitDepth))
+	binary.BigEndian.PutUint32(entry[24:], config.sampleRate<<16)
+	entry = append(entry, mp4Box("alac", "\x00\x00\x00\x00"+string(cookie))...)
+	stsd := append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, mp4Box("alac", string(entry))...)
+
+	stsz := binary.BigEndian.AppendUint32(make([]byte, 8), uint32(len(packets)))
+	var media []byte
+	var packetOffsets []int
+	for _, packet := range packets {
+		stsz = binary.BigEndian.AppendUint32(stsz, uint32(len(packet)))
+		packetOffsets = append(packetOffsets, len(media))
+		media = append(media, packet...)
+	}
+	chunks := (len(packets) + chunkPackets - 1) / chunkPackets
+	stsc := binary.BigEndian.AppendUint32(make([]byte, 4), 2)
+	for _, entry := range [][3]int{{1, chunkPackets, 1}, {chunks, len(packets) - (chunks-1)*chunkPackets, 1}} {
+		for _, field := range entry {
+			stsc = binary.BigEndian.AppendUint32(stsc, uint32(field))
+		}
+	}
+
+	ftyp := mp4Box("ftyp", "M4A \x00\x00\x00\x00M4A isom")
+	moov := func(mediaOffset int) []byte {
+		stco := binary.BigEndian.AppendUint32(make([]byte, 4), uint32(chunks))
+		for chunk := range chunks {
+			stco = binary.BigEndian.AppendUint32(stco, uint32(mediaOffset+packetOffsets[chunk*chunkPackets]))
+		}
+		stbl := concat(mp4Box("stsd", string(stsd)), mp4Box("stsz", string(stsz)), mp4Box("stsc", string(stsc)), mp4Box("stco", string(stco)))
+		trak := mp4Box("trak", string(mp4Box("mdia", string(mp4Box("minf", string(mp4Box("stbl", string(stbl))))))))
+		return mp4Box("moov", string(concat(mp4Box("udta", "tags"), trak)))
+	}
+	mdat := mp4Box("mdat", string(media))
+	if moovLast {
+		return concat(ftyp, mdat, moov(len(ftyp)+boxHeaderSize))
+	}
+	moovSize := len(moov(0))
+	return concat(ftyp, moov(len(ftyp)+moovSize+boxHeaderSize), mdat)
+}
+
+// testSignal returns interleaved samples of the given bit depth, mixing a
+// tone with noise and a stretch of silence.
+func testSignal(channels, bitDepth, frames int) []int32 {
+	amplitude := float64(uint32(1)<<(bitDepth-1)) * 0.6
+	noise := uint32(1)
+	samples := make([]int32, 0, channels*frames)
+	for i := range frames {
+		for channel := range channels {
+			noise = noise*1664525 + 1013904223
+			if i >= frames/3 && i < frames/2 {
+				samples = append(samples, 0)
+				continue
+			}
+			tone := amplitude * math.Sin(float64(i*(channel+1))/7)
+			samples = append(samples, int32(tone)+int32(noise>>(40-bitDepth)))
+		}
+	}
+	return samples
+}
+
+// wavSamples encodes interleaved samples as little-endian WAV data.
+func wavSamples(samples []int32, bitDepth int) []byte {
+	var data []byte
+	for _, sample := range samples {
+		for i := 0; i < bitDepth; i += 8 {
+			data = append(data, byte(sample>>i))
+		}
+	}
+	return data
+}
+
+func TestAudioHasherMatchesAlacAndWav(t *testing.T) {
+	fir := alacEncoding{coefs: []int16{900, -300, 120, -40}, denShift: 9}
+	tests := []struct {
+		name         string
+		channels     int
+		bitDepth     int
+		encoding     alacEncoding
+		chunkPackets int
+		moovLast     bool
+	}{
+		{"uncompressed mono", 1, 16, alacEncoding{escape: true}, 4, false},
+		{"uncompressed stereo", 2, 16, alacEncoding{escape: true}, 4, false},
+		{"uncompressed 24-bit", 2, 24, alacEncoding{escape: true}, 4, false},
+		{"no prediction", 1, 16, alacEncoding{denShift: 9}, 4, false},
+		{"adaptive prediction", 1, 16, fir, 4, false},
+		{"first-order prediction", 1, 16, alacEncoding{firstOrder: true, coefs: fir.coefs, denShift: 9}, 4, false},
+		{"stereo without mixing", 2, 16, fir, 4, false},
+		{"mixed stereo", 2, 16, alacEncoding{coefs: fir.coefs, denShift: 9, mixBits: 2, mixRes: 3}, 4, false},
+		{"shifted 24-bit", 2, 24, alacEncoding{coefs: fir.coefs, denShift: 9, mixBits: 2, mixRes: 2, bytesShifted: 1}, 4, false},
+		{"shifted 32-bit", 1, 32, alacEncoding{coefs: fir.coefs, denShift: 9, bytesShifted: 2}, 4, false},
+		{"three channels", 3, 16, alacEncoding{coefs: fir.coefs, denShift: 9, mixBits: 2, mixRes: 2}, 4, false},
+		{"packet per chunk", 2, 16, fir, 1, false},
+		{"moov after media data", 2, 16, fir, 3, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := alacConfig{frameLength: 64, bitDepth: test.bitDepth, pb: 40, mb: 10, kb: 14, channels: test.channels, sampleRate: 44100}
+			samples := testSignal(test.channels, test.bitDepth, 300)
+			alac := alacFile(config, samples, test.encoding, test.chunkPackets, test.moovLast)
+			wav := wavFile(wavFormatPCM, test.channels, test.bitDepth, wavSamples(samples, test.bitDepth))
+			samples[100]++
+			other := wavFile(wavFormatPCM, test.channels, test.bitDepth, wavSamples(samples, test.bitDepth))
+
+			h := NewAudioHasher()
+			alacHash, err := h.Hash(bytes.NewReader(alac))
+			if err != nil {
+				t.Fatalf("Hash() of ALAC failed: %v", err)
+			}
+			wavHash, err := h.Hash(bytes.NewReader(wav))
+			if err != nil {
+				t.Fatalf("Hash() of WAV failed: %v", err)
+			}
+			otherHash, err := h.Hash(bytes.NewReader(other))
+			if err != nil {
+				t.Fatalf("Hash() of other WAV failed: %v", err)
+			}
+			if !bytes.Equal(alacHash, wavHash) {
+				t.Errorf("Hash() of ALAC = %x, want %x of the same audio as WAV", alacHash, wavHash)
+			}
+			if bytes.Equal(alacHash, otherHash) {
+				t.Errorf("Hash() of ALAC = %x, also the hash of different audio", alacHash)
+			}
+		})
+	}
+}
+
+func TestAudioHasherRejectsInvalidAlac(t *testing.T) {
+	config := alacConfig{frameLength: 64, bitDepth: 16, pb: 40, mb: 10, kb: 14, channels: 2, sampleRate: 44100}
+	samples := testSignal(2, 16, 200)
+	alac := alacFile(config, samples, alacEncoding{denShift: 9}, 4, false)
+	moovLast := alacFile(config, samples, alacEncoding{denShift: 9}, 4, true)
+	ftyp := mp4Box("ftyp", "isom\x00\x00\x00\x00isom")
+	unsupported := config
+	unsupported.bitDepth = 12
+	// The first packet of moovLast follows the ftyp and mdat box headers.
+	corrupt := append([]byte(nil), moovLast...)
+	corrupt[len(ftyp)+boxHeaderSize] = alacElementEND << 5
+
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"no moov box", concat(ftyp, mp4Box("mdat", "media"))},
+		{"no ALAC track", concat(ftyp, mp4Box("moov", string(mp4Box("trak", ""))), mp4Box("mdat", "media"))},
+		{"unsupported bit depth", alacFile(unsupported, samples, alacEncoding{escape: true}, 4, false)},
+		{"truncated media data", alac[:len(alac)-20]},
+		{"truncated moov box", moovLast[:len(moovLast)-20]},
+		{"packet without channels", corrupt},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewAudioHasher().Hash(bytes.NewReader(test.file))
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Errorf("Hash() error = %v, want a *FormatError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package hasher
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+
+	"github.com/zeebo/blake3"
+	"github.com/zeebo/xxh3"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Algorithm identifies the hash function a Hasher uses to digest content.
+type Algorithm string
+
+const (
+	// SHA256 is SHA-256, the default algorithm.
+	SHA256 Algorithm = "sha256"
+	// SHA512 is SHA-512.
+	SHA512 Algorithm = "sha512"
+	// SHA1 is SHA-1. It is fast but no longer collision-resistant.
+	SHA1 Algorithm = "sha1"
+	// BLAKE2b is BLAKE2b with a 512-bit digest.
+	BLAKE2b Algorithm = "blake2b"
+	// BLAKE3 is BLAKE3 with a 256-bit digest.
+	BLAKE3 Algorithm = "blake3"
+	// XXH3 is the 128-bit variant of the non-cryptographic xxHash3.
+	XXH3 Algorithm = "xxh3-128"
+	// MD5 is MD5. It is what FLAC embeds in its STREAMINFO block, and is
+	// only used by the FLAC hasher with WithStreamInfoMD5, so it cannot be
+	// selected with WithAlgorithm.
+	MD5 Algorithm = "md5"
+
+	// DHash is the 64-bit difference hash computed by the perceptual hasher.
+	// It describes what an image looks like rather than its exact content,
+	// so it cannot be selected with WithAlgorithm.
+	DHash Algorithm = "dhash-64"
+
+	// MinHash is the MinHash signature computed by the minhash hasher. Like
+	// DHash, it cannot be selected with WithAlgorithm.
+	MinHash Algorithm = "minhash-128"
+)
+
+// algorithms lists the selectable algorithms in presentation order.
+var algorithms = []Algorithm{SHA256, SHA512, SHA1, BLAKE2b, BLAKE3, XXH3}
+
+// hashConstructors maps each algorithm computed by a hash.Hash to its
+// constructor.
+var hashConstructors = map[Algorithm]func() hash.Hash{
+	SHA256:  sha256.New,
+	SHA512:  sha512.New,
+	SHA1:    sha1.New,
+	BLAKE2b: newBlake2b,
+	BLAKE3:  func() hash.Hash { return blake3.New() },
+	XXH3:    func() hash.Hash { return xxh3.New128() },
+	MD5:     md5.New,
+}
+
+// Algorithms returns all algorithms selectable with WithAlgorithm.
+func Algorithms() []Algorithm {
+	return append([]Algorithm(nil), algorithms...)
+}
+
+// ParseAlgorithm returns the selectable Algorithm named by name.
+// Returns an error if the algorithm is not supported.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	for _, algorithm := range algorithms {
+		if string(algorithm) == name {
+			return algorithm, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported hash algorithm %q", name)
+}
+
+// New returns a new hash.Hash computing the algorithm.
+//
+// Panics if the algorithm is not supported; use ParseAlgorithm to validate
+// untrusted names.
+func (a Algorithm) New() hash.Hash {
+	constructor, ok := hashConstructors[a]
+	if !ok {
+		panic(fmt.Sprintf("unsupported hash algorithm %q", string(a)))
+	}
+	return constructor()
+}
+
+func newBlake2b() hash.Hash {
+	// New512 only fails for keys longer than 64 bytes.
+	h, _ := blake2b.New512(nil)
+	return h
+}
@@ -0,0 +1,164 @@
+package hasher
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"sync"
+)
+
+// audioSniffLength is the number of leading bytes decoders may inspect.
+const audioSniffLength = 16
+
+func init() {
+	Register(Registration{
+		Name:        "audio",
+		Description: "Hash decoded lossless audio, regardless of container format",
+		New:         NewAudioHasher,
+	})
+}
+
+// PCMFormat describes the layout of decoded audio samples.
+type PCMFormat struct {
+	// SampleRate is the number of samples per second and channel.
+	SampleRate uint32
+	// Channels is the number of interleaved channels.
+	Channels uint16
+	// BitsPerSample is the number of significant bits in each sample.
+	BitsPerSample uint16
+}
+
+// PCMWriter receives decoded audio in canonical form.
+type PCMWriter interface {
+	// WriteFormat records the format of the samples. It must be called
+	// exactly once, before any samples are written.
+	WriteFormat(format PCMFormat) error
+
+	// WriteSamples adds interleaved samples. Each sample holds the
+	// sign-extended value of BitsPerSample significant bits, so 8-bit
+	// unsigned and left-justified samples must be converted first.
+	WriteSamples(samples []int32) error
+}
+
+// AudioDecoder decodes an audio container into canonical PCM.
+//
+// Implementations should be stateless and safe for concurrent use.
+type AudioDecoder interface {
+	// Detect reports whether head, the first bytes of a stream, identifies
+	// a format the decoder handles. head may be shorter than expected.
+	Detect(head []byte) bool
+
+	// Decode reads the stream from r and writes its audio to w.
+	Decode(r io.Reader, w PCMWriter) error
+}
+
+var (
+	audioDecoders     []AudioDecoder
+	audioDecodersLock sync.RWMutex
+)
+
+// RegisterAudioDecoder makes decoder available to audio hashers created
+// afterwards. Decoders are tried in registration order.
+func RegisterAudioDecoder(decoder AudioDecoder) {
+	audioDecodersLock.Lock()
+	defer audioDecodersLock.Unlock()
+
+	audioDecoders = append(audioDecoders, decoder)
+}
+
+// audioHasher computes hashes of decoded audio in a canonical PCM form.
+type audioHasher struct {
+	algorithm Algorithm
+	decoders  []AudioDecoder
+}
+
+// NewAudioHasher returns a Hasher that decodes lossless audio and hashes its
+// canonical PCM representation with the algorithm selected by options,
+// SHA-256 by default.
+//
+// The canonical representation consists of the sample rate, channel count
+// and bit depth followed by the interleaved samples, so identical audio
+// hashes the same whether it is stored as FLAC, WAV, AIFF or ALAC. The
+// container is detected from the content using the registered AudioDecoder
+// implementations. Returns a *FormatError if no decoder recognizes the input
+// or it cannot be decoded, such as MP4 files holding lossy audio.
+//
+// ALAC is read from MP4 files. When their moov box follows the media data,
+// the media data is held in memory until the sample tables are known.
+func NewAudioHasher(options ...Option) Hasher {
+	c := newConfig(options)
+
+	audioDecodersLock.RLock()
+	defer audioDecodersLock.RUnlock()
+
+	return &audioHasher{
+		algorithm: c.algorithm,
+		decoders:  append([]AudioDecoder(nil), audioDecoders...),
+	}
+}
+
+func (h *audioHasher) Hash(r io.Reader) ([]byte, error) {
+	reads := &readErrorRecorder{r: r}
+	buffered := bufio.NewReader(reads)
+	head, _ := buffered.Peek(audioSniffLength)
+
+	for _, decoder := range h.decoders {
+		if !decoder.Detect(head) {
+			continue
+		}
+		writer := &pcmHashWriter{hash: h.algorithm.New()}
+		if err := decoder.Decode(buffered, writer); err != nil {
+			return nil, formatError("audio", err, reads)
+		}
+		if !writer.formatWritten {
+			return nil, errors.New("audio decoder wrote no format")
+		}
+		return writer.hash.Sum(nil), nil
+	}
+	return nil, formatError("audio", errors.New("unsupported audio format"), reads)
+}
+
+func (h *audioHasher) Algorithm() Algorithm {
+	return h.algorithm
+}
+
+func (h *audioHasher) Name() string {
+	return "audio"
+}
+
+// pcmHashWriter is a PCMWriter that feeds the canonical representation of
+// the audio into a hash.
+type pcmHashWriter struct {
+	hash          hash.Hash
+	buffer        []byte
+	formatWritten bool
+}
+
+func (w *pcmHashWriter) WriteFormat(format PCMFormat) error {
+	if w.formatWritten {
+		return errors.New("audio format written twice")
+	}
+	w.formatWritten = true
+
+	var header [8]byte
+	binary.LittleEndian.PutUint32(header[0:], format.SampleRate)
+	binary.LittleEndian.PutUint16(header[4:], format.Channels)
+	binary.LittleEndian.PutUint16(header[6:], format.BitsPerSample)
+	_, err := w.hash.Write(header[:])
+	return err
+}
+
+func (w *pcmHashWriter) WriteSamples(samples []int32) error {
+	if !w.formatWritten {
+		return errors.New("audio samples written before format")
+	}
+
+	w.buffer = w.buffer[:0]
+	for _, sample := range samples {
+		w.buffer = binary.LittleEndian.AppendUint32(w.buffer, uint32(sample))
+	}
+	_, err := w.hash.Write(w.buffer)
+	return err
+}
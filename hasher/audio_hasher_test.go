@@ -0,0 +1,132 @@
+package hasher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// sampleRate44100 is 44100 as an 80-bit extended precision number.
+var sampleRate44100 = []byte{0x40, 0x0e, 0xac, 0x44, 0, 0, 0, 0, 0, 0}
+
+// riffChunk returns a RIFF or IFF chunk with the given body, padded to an
+// even size.
+func riffChunk(order binary.ByteOrder, id string, body []byte) []byte {
+	chunk := append([]byte(id), 0, 0, 0, 0)
+	order.PutUint32(chunk[4:], uint32(len(body)))
+	chunk = append(chunk, body...)
+	if len(body)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// wavFile returns a WAVE file holding the given little-endian samples,
+// inserting extra chunks between the fmt and data chunks.
+func wavFile(tag uint16, channels, bits int, data []byte, extra ...[]byte) []byte {
+	format := make([]byte, 16)
+	binary.LittleEndian.PutUint16(format[0:], tag)
+	binary.LittleEndian.PutUint16(format[2:], uint16(channels))
+	binary.LittleEndian.PutUint32(format[4:], 44100)
+	binary.LittleEndian.PutUint16(format[12:], uint16(channels*bits/8))
+	binary.LittleEndian.PutUint16(format[14:], uint16(bits))
+
+	body := []byte("WAVE")
+	body = append(body, riffChunk(binary.LittleEndian, "fmt ", format)...)
+	body = append(body, concat(extra...)...)
+	body = append(body, riffChunk(binary.LittleEndian, "data", data)...)
+	return riffChunk(binary.LittleEndian, "RIFF", body)
+}
+
+// aiffFile returns an AIFF file of the given form type holding data. For
+// AIFF-C, compression names the encoding of the samples.
+func aiffFile(form, compression string, channels, bits int, data []byte) []byte {
+	common := make([]byte, 8)
+	binary.BigEndian.PutUint16(common[0:], uint16(channels))
+	binary.BigEndian.PutUint16(common[6:], uint16(bits))
+	common = append(common, sampleRate44100...)
+	if form == "AIFC" {
+		common = append(common, compression...)
+	}
+
+	body := []byte(form)
+	body = append(body, riffChunk(binary.BigEndian, "COMM", common)...)
+	body = append(body, riffChunk(binary.BigEndian, "SSND", append(make([]byte, 8), data...))...)
+	return riffChunk(binary.BigEndian, "FORM", body)
+}
+
+func TestAudioHasherMatchesAcrossContainers(t *testing.T) {
+	samples := []int16{1, -2, 300, -32768}
+	var little, big []byte
+	for _, sample := range samples {
+		little = binary.LittleEndian.AppendUint16(little, uint16(sample))
+		big = binary.BigEndian.AppendUint16(big, uint16(sample))
+	}
+	other := append([]byte{}, little...)
+	other[0] = 2
+
+	tests := []struct {
+		name string
+		a, b []byte
+		same bool
+	}{
+		{"WAV and AIFF", wavFile(wavFormatPCM, 2, 16, little), aiffFile("AIFF", "", 2, 16, big), true},
+		{"WAV and big-endian AIFF-C", wavFile(wavFormatPCM, 2, 16, little), aiffFile("AIFC", "twos", 2, 16, big), true},
+		{"WAV and little-endian AIFF-C", wavFile(wavFormatPCM, 2, 16, little), aiffFile("AIFC", "sowt", 2, 16, little), true},
+		{"8-bit WAV and AIFF", wavFile(wavFormatPCM, 1, 8, []byte{0x00, 0x80, 0xff}), aiffFile("AIFF", "", 1, 8, []byte{0x80, 0x00, 0x7f}), true},
+		{"WAV with extra chunk", wavFile(wavFormatPCM, 2, 16, little), wavFile(wavFormatPCM, 2, 16, little, riffChunk(binary.LittleEndian, "LIST", []byte("INFOtag"))), true},
+		{"different samples", wavFile(wavFormatPCM, 2, 16, little), wavFile(wavFormatPCM, 2, 16, other), false},
+		{"different channel count", wavFile(wavFormatPCM, 2, 16, little), wavFile(wavFormatPCM, 1, 16, little), false},
+	}
+	h := NewAudioHasher()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := h.Hash(bytes.NewReader(test.a))
+			if err != nil {
+				t.Fatalf("Hash() of first file failed: %v", err)
+			}
+			b, err := h.Hash(bytes.NewReader(test.b))
+			if err != nil {
+				t.Fatalf("Hash() of second file failed: %v", err)
+			}
+			if bytes.Equal(a, b) != test.same {
+				t.Errorf("Hash() = %x and %x, want same = %v", a, b, test.same)
+			}
+		})
+	}
+}
+
+func TestAudioHasherRejectsInvalidAudio(t *testing.T) {
+	data := []byte{1, 0, 2, 0, 3, 0, 4, 0}
+	wav := wavFile(wavFormatPCM, 2, 16, data)
+	aiff := aiffFile("AIFF", "", 2, 16, data)
+	noFormat := riffChunk(binary.LittleEndian, "RIFF", append([]byte("WAVE"), riffChunk(binary.LittleEndian, "data", data)...))
+
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"unsupported format", []byte("OggS")},
+		{"truncated WAV data", wav[:len(wav)-3]},
+		{"WAV without data chunk", wav[:len(wav)-len(data)-8]},
+		{"WAV data before fmt", noFormat},
+		{"floating-point WAV", wavFile(3, 2, 32, data)},
+		{"WAV without channels", wavFile(wavFormatPCM, 0, 16, data)},
+		{"truncated AIFF data", aiff[:len(aiff)-3]},
+		{"compressed AIFF-C", aiffFile("AIFC", "ima4", 2, 16, data)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewAudioHasher().Hash(bytes.NewReader(test.file))
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Errorf("Hash() error = %v, want a *FormatError", err)
+			}
+		})
+	}
+}
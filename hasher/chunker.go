@@ -0,0 +1,65 @@
+package hasher
+
+import (
+	"fmt"
+	"io"
+)
+
+// Chunk is a piece of content produced by a Chunker.
+type Chunk struct {
+	// Hash is the digest of the chunk's content.
+	Hash []byte
+	// Size is the length of the chunk in bytes.
+	Size int
+}
+
+// Chunker splits content into chunks for block-level deduplication.
+//
+// Implementations should be stateless and safe for concurrent use.
+type Chunker interface {
+	// Chunk reads all data from r and passes its chunks to visit in order.
+	// Returns an error if reading fails.
+	Chunk(r io.Reader, visit func(Chunk)) error
+}
+
+// ChunkingMethod identifies the algorithm a Chunker uses to find chunk
+// boundaries.
+type ChunkingMethod string
+
+const (
+	// FastCDC is content-defined chunking with the FastCDC algorithm.
+	FastCDC ChunkingMethod = "fastcdc"
+)
+
+// chunkerConstructors maps each supported chunking method to its Chunker
+// constructor.
+var chunkerConstructors = map[ChunkingMethod]func(options ...Option) Chunker{
+	FastCDC: NewFastCDCChunker,
+}
+
+// ChunkingMethods returns all supported chunking methods.
+func ChunkingMethods() []ChunkingMethod {
+	return []ChunkingMethod{FastCDC}
+}
+
+// ParseChunkingMethod returns the ChunkingMethod named by name.
+// Returns an error if the method is not supported.
+func ParseChunkingMethod(name string) (ChunkingMethod, error) {
+	method := ChunkingMethod(name)
+	if _, ok := chunkerConstructors[method]; !ok {
+		return "", fmt.Errorf("unsupported chunking method %q", name)
+	}
+	return method, nil
+}
+
+// NewChunker returns a new Chunker using the method, configured with options.
+//
+// Panics if the method is not supported; use ParseChunkingMethod to validate
+// untrusted names.
+func (m ChunkingMethod) NewChunker(options ...Option) Chunker {
+	constructor, ok := chunkerConstructors[m]
+	if !ok {
+		panic(fmt.Sprintf("unsupported chunking method %q", string(m)))
+	}
+	return constructor(options...)
+}
@@ -1,25 +1,44 @@
 package hasher
 
 import (
-	"crypto/sha256"
 	"io"
 )
 
-// defaultHasher computes SHA-256 hashes of raw file content.
-type defaultHasher struct{}
+func init() {
+	Register(Registration{
+		Name:        "default",
+		Description: "Hash raw file content",
+		New:         NewDefaultHasher,
+	})
+}
+
+// defaultHasher computes hashes of raw file content.
+type defaultHasher struct {
+	algorithm Algorithm
+}
 
-// NewDefaultHasher returns a Hasher that computes SHA-256 hashes.
+// NewDefaultHasher returns a Hasher that hashes raw content with the
+// algorithm selected by options, SHA-256 by default.
 //
 // The returned hasher streams data through the hash function without
 // loading the entire content into memory, making it suitable for large files.
-func NewDefaultHasher() Hasher {
-	return &defaultHasher{}
+func NewDefaultHasher(options ...Option) Hasher {
+	c := newConfig(options)
+	return &defaultHasher{algorithm: c.algorithm}
 }
 
 func (h *defaultHasher) Hash(r io.Reader) ([]byte, error) {
-	hash := sha256.New()
+	hash := h.algorithm.New()
 	if _, err := io.Copy(hash, r); err != nil {
 		return nil, err
 	}
 	return hash.Sum(nil), nil
 }
+
+func (h *defaultHasher) Algorithm() Algorithm {
+	return h.algorithm
+}
+
+func (h *defaultHasher) Name() string {
+	return "default"
+}
@@ -0,0 +1,109 @@
+package hasher
+
+import (
+	"errors"
+	"io"
+)
+
+const (
+	// fastCDCMinSize is the minimum size of a FastCDC chunk.
+	fastCDCMinSize = 2 << 10
+	// fastCDCAverageSize is the targeted average size of a FastCDC chunk.
+	fastCDCAverageSize = 8 << 10
+	// fastCDCMaxSize is the maximum size of a FastCDC chunk.
+	fastCDCMaxSize = 64 << 10
+	// fastCDCSeed seeds the derivation of the gear table.
+	fastCDCSeed = 0x66617374636463
+
+	// fastCDCMaskSmall selects the top 15 bits of the rolling hash, two more
+	// than the average chunk size implies, making cut points unlikely before
+	// the average size is reached.
+	fastCDCMaskSmall uint64 = 0xfffe_0000_0000_0000
+	// fastCDCMaskLarge selects the top 11 bits of the rolling hash, two fewer
+	// than the average chunk size implies, making cut points likely once the
+	// average size is exceeded.
+	fastCDCMaskLarge uint64 = 0xffe0_0000_0000_0000
+)
+
+// fastCDCGear maps each byte to a random value mixed into the rolling hash.
+var fastCDCGear = func() [256]uint64 {
+	var gear [256]uint64
+	state := uint64(fastCDCSeed)
+	for i := range gear {
+		state = mix64(state)
+		gear[i] = state
+	}
+	return gear
+}()
+
+// fastCDCChunker splits content into chunks with FastCDC.
+type fastCDCChunker struct {
+	algorithm Algorithm
+}
+
+// NewFastCDCChunker returns a Chunker that splits content with FastCDC and
+// digests each chunk with the algorithm selected by options, SHA-256 by
+// default.
+//
+// FastCDC places chunk boundaries where a rolling gear hash of the content
+// matches a mask, so inserting or removing bytes only changes the chunks
+// around the edit rather than shifting every later boundary. Chunks are
+// between 2 KiB and 64 KiB long and average about 8 KiB, using normalized
+// chunking to keep sizes close to the average.
+func NewFastCDCChunker(options ...Option) Chunker {
+	c := newConfig(options)
+	return &fastCDCChunker{algorithm: c.algorithm}
+}
+
+func (c *fastCDCChunker) Chunk(r io.Reader, visit func(Chunk)) error {
+	buffer := make([]byte, fastCDCMaxSize)
+	filled := 0
+	eof := false
+
+	for {
+		if !eof && filled < len(buffer) {
+			n, err := io.ReadFull(r, buffer[filled:])
+			filled += n
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				eof = true
+			} else if err != nil {
+				return err
+			}
+		}
+		if filled == 0 {
+			return nil
+		}
+
+		size := fastCDCCutPoint(buffer[:filled])
+		hash := c.algorithm.New()
+		_, _ = hash.Write(buffer[:size])
+		visit(Chunk{Hash: hash.Sum(nil), Size: size})
+
+		filled = copy(buffer, buffer[size:filled])
+	}
+}
+
+// fastCDCCutPoint returns the length of the chunk starting at data.
+func fastCDCCutPoint(data []byte) int {
+	n := len(data)
+	if n <= fastCDCMinSize {
+		return n
+	}
+	normal := min(fastCDCAverageSize, n)
+
+	var fingerprint uint64
+	i := fastCDCMinSize
+	for ; i < normal; i++ {
+		fingerprint = fingerprint<<1 + fastCDCGear[data[i]]
+		if fingerprint&fastCDCMaskSmall == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fingerprint = fingerprint<<1 + fastCDCGear[data[i]]
+		if fingerprint&fastCDCMaskLarge == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
@@ -0,0 +1,157 @@
+package hasher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/rand"
+	"testing"
+)
+
+// randomContent returns size bytes of reproducible pseudo-random content.
+func randomContent(seed int64, size int) []byte {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(content)
+	return content
+}
+
+// fastCDCChunks returns the chunks of content.
+func fastCDCChunks(t *testing.T, content []byte) []Chunk {
+	t.Helper()
+	var chunks []Chunk
+	err := NewFastCDCChunker().Chunk(bytes.NewReader(content), func(chunk Chunk) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("Chunk() failed: %v", err)
+	}
+	return chunks
+}
+
+func TestFastCDCChunkerBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		chunks int
+	}{
+		{"empty", 0, 0},
+		{"one byte", 1, 1},
+		{"minimum size", fastCDCMinSize, 1},
+		{"maximum size", fastCDCMaxSize, -1},
+		{"several buffers", 3*fastCDCMaxSize + 17, -1},
+		{"one mebibyte", 1 << 20, -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content := randomContent(1, test.size)
+			chunks := fastCDCChunks(t, content)
+			if test.chunks >= 0 && len(chunks) != test.chunks {
+				t.Fatalf("Chunk() produced %d chunks, want %d", len(chunks), test.chunks)
+			}
+
+			offset := 0
+			for i, chunk := range chunks {
+				if chunk.Size > fastCDCMaxSize {
+					t.Errorf("chunk %d is %d bytes, above the maximum", i, chunk.Size)
+				}
+				if i < len(chunks)-1 && chunk.Size < fastCDCMinSize {
+					t.Errorf("chunk %d is %d bytes, below the minimum", i, chunk.Size)
+				}
+				sum := sha256.Sum256(content[offset : offset+chunk.Size])
+				if !bytes.Equal(chunk.Hash, sum[:]) {
+					t.Errorf("chunk %d hash = %x, want %x", i, chunk.Hash, sum)
+				}
+				offset += chunk.Size
+			}
+			if offset != len(content) {
+				t.Errorf("chunks cover %d bytes, want %d", offset, len(content))
+			}
+		})
+	}
+}
+
+func TestFastCDCChunkerResistsShifts(t *testing.T) {
+	content := randomContent(2, 1<<20)
+	tests := []struct {
+		name   string
+		edited []byte
+	}{
+		{"unchanged", content},
+		{"bytes inserted at start", concat([]byte("inserted"), content)},
+		{"bytes removed from start", content[100:]},
+		{"bytes inserted in middle", concat(content[:1<<19], []byte("inserted"), content[1<<19:])},
+		{"byte changed in middle", concat(content[:1<<19], []byte{^content[1<<19]}, content[1<<19+1:])},
+	}
+
+	original := map[string]bool{}
+	chunks := fastCDCChunks(t, content)
+	for _, chunk := range chunks {
+		original[string(chunk.Hash)] = true
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			edited := fastCDCChunks(t, test.edited)
+			changed := 0
+			for _, chunk := range edited {
+				if !original[string(chunk.Hash)] {
+					changed++
+				}
+			}
+			// An edit changes the chunk containing it and at most the next
+			// one, whose start moves.
+			if changed > 2 {
+				t.Errorf("%d of %d chunks changed, want at most 2", changed, len(edited))
+			}
+		})
+	}
+}
+
+func TestFastCDCCutPoint(t *testing.T) {
+	content := randomContent(3, 4*fastCDCMaxSize)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"below minimum", content[:fastCDCMinSize-1]},
+		{"at minimum", content[:fastCDCMinSize]},
+		{"below average", content[:fastCDCAverageSize-1]},
+		{"at maximum", content[:fastCDCMaxSize]},
+		{"offset window", content[1000 : 1000+fastCDCMaxSize]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := len(test.data)
+			cut := fastCDCCutPoint(test.data)
+			switch {
+			case n <= fastCDCMinSize && cut != n:
+				t.Errorf("fastCDCCutPoint() = %d, want the whole %d bytes", cut, n)
+			case cut < min(n, fastCDCMinSize) || cut > n:
+				t.Errorf("fastCDCCutPoint() = %d, want between %d and %d", cut, fastCDCMinSize, n)
+			}
+			// The cut point depends only on the data before it.
+			if again := fastCDCCutPoint(test.data[:cut]); cut > fastCDCMinSize && again != cut {
+				t.Errorf("fastCDCCutPoint() of the chunk alone = %d, want %d", again, cut)
+			}
+		})
+	}
+}
+
+func TestParseChunkingMethod(t *testing.T) {
+	tests := []struct {
+		name   string
+		method ChunkingMethod
+		ok     bool
+	}{
+		{"fastcdc", FastCDC, true},
+		{"FastCDC", "", false},
+		{"rabin", "", false},
+		{"", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method, err := ParseChunkingMethod(test.name)
+			if method != test.method || (err == nil) != test.ok {
+				t.Errorf("ParseChunkingMethod(%q) = %q, %v, want %q, ok = %v", test.name, method, err, test.method, test.ok)
+			}
+		})
+	}
+}
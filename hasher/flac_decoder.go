@@ -0,0 +1,69 @@
+package hasher
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+func init() {
+	RegisterAudioDecoder(flacDecoder{})
+}
+
+// flacDecoder decodes FLAC streams for the audio hasher.
+type flacDecoder struct{}
+
+func (flacDecoder) Detect(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("fLaC"))
+}
+
+func (flacDecoder) Decode(r io.Reader, w PCMWriter) error {
+	stream, err := flac.New(r)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	err = w.WriteFormat(PCMFormat{
+		SampleRate:    stream.Info.SampleRate,
+		Channels:      uint16(stream.Info.NChannels),
+		BitsPerSample: uint16(stream.Info.BitsPerSample),
+	})
+	if err != nil {
+		return err
+	}
+
+	var samples []int32
+	return decodeFlacFrames(stream, func(frame *frame.Frame) error {
+		if len(frame.Subframes) == 0 {
+			return nil
+		}
+		samples = samples[:0]
+		for i := range frame.Subframes[0].Samples {
+			for _, subframe := range frame.Subframes {
+				samples = append(samples, subframe.Samples[i])
+			}
+		}
+		return w.WriteSamples(samples)
+	})
+}
+
+// decodeFlacFrames decodes the remaining frames of stream, calling visit
+// with each of them. It is shared by the FLAC decoder of the audio hasher and
+// the flac hasher, so both read streams the same way.
+func decodeFlacFrames(stream *flac.Stream, visit func(*frame.Frame) error) error {
+	for {
+		frame, err := stream.ParseNext()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := visit(frame); err != nil {
+			return err
+		}
+	}
+}
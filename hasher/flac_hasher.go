@@ -1,52 +1,173 @@
 package hasher
 
 import (
-	"crypto/sha256"
+	"bytes"
+	"crypto/md5"
+	"fmt"
 	"hash"
 	"io"
 
 	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
 )
 
-type flacHasher struct{}
+func init() {
+	Register(Registration{
+		Name:        "flac",
+		Description: "Hash decoded FLAC audio samples, ignoring metadata",
+		New:         NewFlacHasher,
+	})
+}
+
+type flacHasher struct {
+	algorithm      Algorithm
+	streamInfoMD5  bool
+	verify         bool
+	integrityCheck bool
+}
 
-// NewFlacHasher returns a Hasher that computes SHA-256 hashes of decoded
-// FLAC audio samples.
+// NewFlacHasher returns a Hasher that hashes decoded FLAC audio samples with
+// the algorithm selected by options, SHA-256 by default.
 //
 // Unlike NewDefaultHasher which hashes raw file bytes, this hasher decodes
 // the FLAC stream and hashes only the PCM audio samples. This means two
 // FLAC files with identical audio but different metadata or encoding
 // parameters will produce the same hash.
 //
-// The hasher expects the reader to contain valid FLAC data. Returns an
-// error if the input is not valid FLAC format.
-func NewFlacHasher() Hasher {
-	return &flacHasher{}
+// With WithStreamInfoMD5, the hasher skips decoding and uses the MD5 of the
+// audio samples recorded in the STREAMINFO block instead, unless it is unset
+// or WithVerify is given.
+//
+// With WithIntegrityCheck, the hasher also computes the MD5 of the decoded
+// samples and compares it with STREAMINFO. Mismatches, frame CRC errors and
+// truncated streams are reported as an *IntegrityError.
+//
+// The hasher expects the reader to contain valid FLAC data. Returns a
+// *FormatError if the input is not valid FLAC format.
+func NewFlacHasher(options ...Option) Hasher {
+	c := newConfig(options)
+	h := &flacHasher{
+		algorithm:      c.algorithm,
+		streamInfoMD5:  c.streamInfoMD5,
+		verify:         c.verify,
+		integrityCheck: c.integrityCheck,
+	}
+	if h.streamInfoMD5 {
+		h.algorithm = MD5
+	}
+	return h
 }
 
 func (h *flacHasher) Hash(r io.Reader) ([]byte, error) {
-	stream, err := flac.New(r)
+	reads := &readErrorRecorder{r: r}
+	stream, err := flac.New(reads)
 	if err != nil {
-		return nil, err
+		if h.integrityCheck {
+			return nil, classifyHeaderError(err)
+		}
+		return nil, formatError("FLAC", err, reads)
 	}
 	defer stream.Close()
 
-	hash := sha256.New()
-	if err := h.hashAudioFrames(stream, hash); err != nil {
+	if h.streamInfoMD5 {
+		return h.hashStreamInfoMD5(stream, reads)
+	}
+
+	hash := h.algorithm.New()
+	if !h.integrityCheck {
+		if _, err := h.hashAudioFrames(stream, reads, hash); err != nil {
+			return nil, err
+		}
+		return hash.Sum(nil), nil
+	}
+
+	checksum := md5.New()
+	samples, err := h.hashAudioFrames(stream, reads, hash, checksum)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStreamInfo(stream, samples, checksum.Sum(nil)); err != nil {
 		return nil, err
 	}
 	return hash.Sum(nil), nil
 }
 
-func (h *flacHasher) hashAudioFrames(stream *flac.Stream, hash hash.Hash) error {
-	for {
-		frame, err := stream.ParseNext()
-		if err == io.EOF {
-			return nil
+func (h *flacHasher) Algorithm() Algorithm {
+	return h.algorithm
+}
+
+func (h *flacHasher) Name() string {
+	return "flac"
+}
+
+// hashStreamInfoMD5 returns the MD5 signature of the stream's audio, taking it
+// from STREAMINFO when set and decoding the frames otherwise or to verify it.
+func (h *flacHasher) hashStreamInfoMD5(stream *flac.Stream, reads *readErrorRecorder) ([]byte, error) {
+	embedded := stream.Info.MD5sum
+	unset := embedded == [md5.Size]byte{}
+	if !unset && !h.verify {
+		return embedded[:], nil
+	}
+
+	hash := md5.New()
+	samples, err := h.hashAudioFrames(stream, reads, hash)
+	if err != nil {
+		return nil, err
+	}
+	decoded := hash.Sum(nil)
+	if err := checkStreamInfo(stream, samples, decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// hashAudioFrames adds the decoded samples of all remaining frames of stream,
+// read through reads, to each of hashes, returning the number of samples per
+// channel. When checking integrity, errors caused by corrupt or truncated
+// frames are reported as an *IntegrityError, and as a *FormatError otherwise.
+func (h *flacHasher) hashAudioFrames(stream *flac.Stream, reads *readErrorRecorder, hashes ...hash.Hash) (uint64, error) {
+	var samples uint64
+	err := decodeFlacFrames(stream, func(frame *frame.Frame) error {
+		samples += uint64(frame.BlockSize)
+		for _, hash := range hashes {
+			frame.Hash(hash)
 		}
-		if err != nil {
-			return err
+		return nil
+	})
+	if err != nil {
+		if h.integrityCheck {
+			return 0, classifyFrameError(err, reads)
+		}
+		return 0, formatError("FLAC", err, reads)
+	}
+	return samples, nil
+}
+
+// checkStreamInfo compares the number of samples per channel and the MD5 of
+// the decoded audio with the ones recorded in STREAMINFO, if any. A stream
+// with fewer samples than recorded was truncated, possibly at a frame
+// boundary where decoding alone cannot tell, while one with more samples
+// holds frames STREAMINFO does not account for.
+func checkStreamInfo(stream *flac.Stream, samples uint64, decoded []byte) error {
+	if recorded := stream.Info.NSamples; recorded != 0 && samples < recorded {
+		return &IntegrityError{
+			Failure: IntegrityTruncated,
+			Err:     fmt.Errorf("decoded %d samples per channel, STREAMINFO records %d", samples, recorded),
 		}
-		frame.Hash(hash)
+	}
+	if recorded := stream.Info.NSamples; recorded != 0 && samples > recorded {
+		return &IntegrityError{
+			Failure: IntegrityLengthMismatch,
+			Err:     fmt.Errorf("decoded %d samples per channel, STREAMINFO records %d", samples, recorded),
+		}
+	}
+
+	embedded := stream.Info.MD5sum
+	if embedded == [md5.Size]byte{} || bytes.Equal(decoded, embedded[:]) {
+		return nil
+	}
+	return &IntegrityError{
+		Failure: IntegrityChecksumMismatch,
+		Err:     fmt.Errorf("decoded audio MD5 %x does not match STREAMINFO MD5 %x", decoded, embedded),
 	}
 }
@@ -0,0 +1,38 @@
+package hasher
+
+import (
+	"crypto/md5"
+	"errors"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+func TestCheckStreamInfo(t *testing.T) {
+	decoded := md5.Sum([]byte("audio"))
+	tests := []struct {
+		name    string
+		info    meta.StreamInfo
+		samples uint64
+		failure IntegrityFailure
+	}{
+		{"matching", meta.StreamInfo{NSamples: 100, MD5sum: decoded}, 100, ""},
+		{"nothing recorded", meta.StreamInfo{}, 100, ""},
+		{"fewer samples", meta.StreamInfo{NSamples: 100, MD5sum: decoded}, 90, IntegrityTruncated},
+		{"more samples", meta.StreamInfo{NSamples: 100, MD5sum: decoded}, 110, IntegrityLengthMismatch},
+		{"different MD5", meta.StreamInfo{NSamples: 100, MD5sum: md5.Sum([]byte("other"))}, 100, IntegrityChecksumMismatch},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkStreamInfo(&flac.Stream{Info: &test.info}, test.samples, decoded[:])
+			var integrityErr *IntegrityError
+			switch {
+			case test.failure == "" && err != nil:
+				t.Errorf("checkStreamInfo() = %v, want nil", err)
+			case test.failure != "" && (!errors.As(err, &integrityErr) || integrityErr.Failure != test.failure):
+				t.Errorf("checkStreamInfo() = %v, want a %s failure", err, test.failure)
+			}
+		})
+	}
+}
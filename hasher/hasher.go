@@ -1,11 +1,32 @@
 // Package hasher provides interfaces and implementations for computing
 // content hashes from data streams.
 //
-// The package defines a Hasher interface that abstracts the hashing algorithm,
-// allowing different implementations for various file types. Built-in implementations
-// include:
-//   - DefaultHasher: computes SHA-256 hash of raw file content
-//   - FlacHasher: computes SHA-256 hash of decoded FLAC audio samples
+// The package defines a Hasher interface that abstracts how content is
+// extracted from a stream, allowing different implementations for various
+// file types. Built-in implementations include:
+//   - DefaultHasher: hashes raw file content
+//   - FlacHasher: hashes decoded FLAC audio samples
+//   - AudioHasher: hashes decoded lossless audio through pluggable decoders
+//   - Mp3Hasher: hashes MPEG audio frames, skipping tags
+//   - ImageHasher: hashes JPEG and PNG image data, skipping metadata
+//   - Mp4Hasher: hashes MP4 and QuickTime media data, skipping metadata
+//   - PerceptualHasher: hashes decoded images by appearance
+//   - MinHashHasher: hashes text by the words it contains
+//   - TextHasher: hashes text, ignoring line endings and trailing whitespace
+//   - OfficeHasher: hashes the unpacked parts of zip-based office documents
+//   - StructuredHasher: hashes JSON and YAML data in a canonical form
+//
+// The digest algorithm is selected independently with WithAlgorithm and
+// defaults to SHA-256. Hashers can be registered by name with Register and
+// retrieved with Lookup.
+//
+// PerceptualHasher and MinHashHasher are SimilarityHashers: their hashes
+// can be compared to estimate how similar the hashed content is, rather
+// than only whether it is identical.
+//
+// A Chunker splits content into chunks hashed individually, such as the
+// content-defined chunks of FastCDC, for estimating block-level
+// deduplication.
 package hasher
 
 import (
@@ -20,4 +41,13 @@ type Hasher interface {
 	// Hash reads all data from r and returns the computed hash bytes.
 	// Returns an error if reading fails or the data format is invalid.
 	Hash(r io.Reader) ([]byte, error)
+
+	// Algorithm returns the algorithm producing the hashes.
+	// Hashes computed with different algorithms must never be compared.
+	Algorithm() Algorithm
+
+	// Name returns the namespace of the hashes, identifying how content is
+	// extracted before digesting, such as "default" or "flac".
+	// Hashes from hashers with different names must never be compared.
+	Name() string
 }
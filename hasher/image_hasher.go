@@ -0,0 +1,251 @@
+package hasher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+const (
+	// jpegMarkerSOI starts a JPEG image.
+	jpegMarkerSOI = 0xd8
+	// jpegMarkerEOI ends a JPEG image.
+	jpegMarkerEOI = 0xd9
+	// jpegMarkerSOS starts a scan of entropy-coded data.
+	jpegMarkerSOS = 0xda
+	// jpegMarkerCOM marks a comment segment.
+	jpegMarkerCOM = 0xfe
+)
+
+// pngSignature starts every PNG file.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngPixelChunks lists the ancillary PNG chunks that are hashed as they
+// change the pixels rather than describe the image. tRNS makes colors
+// transparent.
+var pngPixelChunks = map[string]bool{"tRNS": true}
+
+func init() {
+	Register(Registration{
+		Name:        "image",
+		Description: "Hash JPEG and PNG image data, ignoring EXIF, XMP, ICC and other metadata",
+		New:         NewImageHasher,
+	})
+}
+
+// imageHasher computes hashes of the image data in JPEG and PNG files.
+type imageHasher struct {
+	algorithm Algorithm
+}
+
+// NewImageHasher returns a Hasher that hashes the image data of JPEG and PNG
+// files with the algorithm selected by options, SHA-256 by default.
+//
+// For JPEG, the hasher skips APPn segments (EXIF, XMP, ICC profiles and the
+// like) and comments, hashing only the segments that define how the image
+// is coded along with the entropy-coded data. For PNG, it skips ancillary
+// chunks and hashes the critical IHDR and PLTE chunks along with the IDAT
+// stream, regardless of how it is split into chunks. The ancillary tRNS
+// chunk is hashed too, so images differing only in transparency do not
+// match. Neither format is decoded. Returns a *FormatError if the input is
+// neither JPEG nor PNG.
+func NewImageHasher(options ...Option) Hasher {
+	c := newConfig(options)
+	return &imageHasher{algorithm: c.algorithm}
+}
+
+func (h *imageHasher) Hash(r io.Reader) ([]byte, error) {
+	reads := &readErrorRecorder{r: r}
+	reader := bufio.NewReader(reads)
+	head, _ := reader.Peek(len(pngSignature))
+
+	hash := h.algorithm.New()
+	var err error
+	switch {
+	case bytes.HasPrefix(head, []byte{0xff, jpegMarkerSOI}):
+		err = hashJpegImageData(reader, hash)
+	case bytes.HasPrefix(head, pngSignature):
+		err = hashPngImageData(reader, hash)
+	default:
+		err = errors.New("unsupported image format")
+	}
+	if err != nil {
+		return nil, formatError("image", err, reads)
+	}
+	return hash.Sum(nil), nil
+}
+
+func (h *imageHasher) Algorithm() Algorithm {
+	return h.algorithm
+}
+
+func (h *imageHasher) Name() string {
+	return "image"
+}
+
+// hashJpegImageData hashes the coding segments and entropy-coded data of the
+// JPEG image in reader, up to the end of image marker.
+func hashJpegImageData(reader *bufio.Reader, hash hash.Hash) error {
+	if _, err := reader.Discard(2); err != nil {
+		return err
+	}
+
+	marker, err := readJpegMarker(reader)
+	for err == nil && marker != jpegMarkerEOI {
+		if isJpegStandaloneMarker(marker) {
+			marker, err = readJpegMarker(reader)
+			continue
+		}
+
+		var length uint16
+		if err = binary.Read(reader, binary.BigEndian, &length); err != nil {
+			break
+		}
+		if length < 2 {
+			return fmt.Errorf("invalid JPEG segment length %d", length)
+		}
+
+		if isJpegMetadataMarker(marker) {
+			_, err = reader.Discard(int(length) - 2)
+			if err == nil {
+				marker, err = readJpegMarker(reader)
+			}
+			continue
+		}
+
+		_, _ = hash.Write([]byte{0xff, marker, byte(length >> 8), byte(length)})
+		if _, err = io.CopyN(hash, reader, int64(length)-2); err != nil {
+			break
+		}
+		if marker == jpegMarkerSOS {
+			marker, err = hashJpegEntropyData(reader, hash)
+		} else {
+			marker, err = readJpegMarker(reader)
+		}
+	}
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// isJpegStandaloneMarker reports whether marker stands alone without a
+// segment, as restart and TEM markers do.
+func isJpegStandaloneMarker(marker byte) bool {
+	return marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7)
+}
+
+// isJpegMetadataMarker reports whether marker starts an APPn or comment
+// segment, which carry metadata rather than image data.
+func isJpegMetadataMarker(marker byte) bool {
+	return (marker >= 0xe0 && marker <= 0xef) || marker == jpegMarkerCOM
+}
+
+// readJpegMarker reads the next marker, skipping fill bytes.
+func readJpegMarker(reader *bufio.Reader) (byte, error) {
+	b, err := reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != 0xff {
+		return 0, fmt.Errorf("expected JPEG marker, found 0x%02x", b)
+	}
+	for b == 0xff {
+		if b, err = reader.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+	return b, nil
+}
+
+// hashJpegEntropyData hashes the entropy-coded data following a start of
+// scan segment, including stuffed bytes and restart markers. It returns the
+// marker ending the data.
+func hashJpegEntropyData(reader *bufio.Reader, hash hash.Hash) (byte, error) {
+	buffered := bufio.NewWriter(hash)
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != 0xff {
+			_ = buffered.WriteByte(b)
+			continue
+		}
+
+		next, err := reader.ReadByte()
+		for err == nil && next == 0xff {
+			next, err = reader.ReadByte()
+		}
+		if err != nil {
+			return 0, err
+		}
+		// Stuffed zero bytes and restart markers belong to the data.
+		if next == 0x00 || (next >= 0xd0 && next <= 0xd7) {
+			_ = buffered.WriteByte(0xff)
+			_ = buffered.WriteByte(next)
+			continue
+		}
+		return next, buffered.Flush()
+	}
+}
+
+// hashPngImageData hashes the critical chunks and the pngPixelChunks of the
+// PNG image in reader, up to the IEND chunk. IDAT chunks are hashed as a
+// single stream.
+func hashPngImageData(reader *bufio.Reader, hash hash.Hash) error {
+	if _, err := reader.Discard(len(pngSignature)); err != nil {
+		return err
+	}
+
+	inImageData := false
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			return unexpectedEOF(err)
+		}
+		length := int64(binary.BigEndian.Uint32(header[0:]))
+		chunkType := string(header[4:8])
+		if chunkType == "IEND" {
+			return nil
+		}
+
+		// Ancillary chunks have a lowercase first letter.
+		critical := header[4]&0x20 == 0
+		if !critical && !pngPixelChunks[chunkType] {
+			if _, err := reader.Discard(int(length) + 4); err != nil {
+				return unexpectedEOF(err)
+			}
+			continue
+		}
+
+		switch {
+		case chunkType == "IDAT" && inImageData:
+			// Continue the image data stream across chunk boundaries.
+		case chunkType == "IDAT":
+			_, _ = hash.Write(header[4:8])
+		default:
+			_, _ = hash.Write(header[4:8])
+			_, _ = hash.Write(header[0:4])
+		}
+		inImageData = chunkType == "IDAT"
+		if _, err := io.CopyN(hash, reader, length); err != nil {
+			return unexpectedEOF(err)
+		}
+		if _, err := reader.Discard(4); err != nil {
+			return unexpectedEOF(err)
+		}
+	}
+}
+
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package hasher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// jpegSegment returns a JPEG segment with the given marker and payload.
+func jpegSegment(marker byte, payload string) []byte {
+	segment := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// jpegImage returns a JPEG image made of the given segments followed by the
+// entropy-coded data of a single scan.
+func jpegImage(entropyData string, segments ...[]byte) []byte {
+	image := []byte{0xff, jpegMarkerSOI}
+	image = append(image, concat(segments...)...)
+	image = append(image, jpegSegment(jpegMarkerSOS, "scan")...)
+	image = append(image, entropyData...)
+	return append(image, 0xff, jpegMarkerEOI)
+}
+
+// pngChunk returns a PNG chunk with the given type and data. The CRC is not
+// computed, since the hasher does not check it.
+func pngChunk(chunkType, data string) []byte {
+	chunk := make([]byte, 4, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return append(chunk, 0, 0, 0, 0)
+}
+
+// pngImage returns a PNG image made of the given chunks.
+func pngImage(chunks ...[]byte) []byte {
+	image := append([]byte{}, pngSignature...)
+	image = append(image, concat(chunks...)...)
+	return append(image, pngChunk("IEND", "")...)
+}
+
+// imageHashTest describes an image whose hash is compared with the hash of
+// a plain image.
+type imageHashTest struct {
+	name  string
+	image []byte
+	same  bool
+}
+
+func TestImageHasherIgnoresJpegMetadata(t *testing.T) {
+	const entropyData = "\x12\xff\x00\x34\xff\xd0\x56"
+	quantization := jpegSegment(0xdb, "quantization")
+	frame := jpegSegment(0xc0, "frame")
+	plain := jpegImage(entropyData, quantization, frame)
+
+	tests := []imageHashTest{
+		{"EXIF segment", jpegImage(entropyData, jpegSegment(0xe1, "Exif\x00\x00data"), quantization, frame), true},
+		{"ICC profile segment", jpegImage(entropyData, quantization, jpegSegment(0xe2, "ICC_PROFILE"), frame), true},
+		{"comment", jpegImage(entropyData, quantization, frame, jpegSegment(jpegMarkerCOM, "comment")), true},
+		{"fill bytes before marker", jpegImage(entropyData, quantization, append([]byte{0xff}, frame...)), true},
+		{"different quantization", jpegImage(entropyData, jpegSegment(0xdb, "other"), frame), false},
+		{"different entropy data", jpegImage("\x12\xff\x00\x34\xff\xd1\x56", quantization, frame), false},
+	}
+	testImageHasher(t, plain, tests)
+}
+
+func TestImageHasherIgnoresPngMetadata(t *testing.T) {
+	header := pngChunk("IHDR", "header")
+	plain := pngImage(header, pngChunk("IDAT", "abcdef"))
+
+	tests := []imageHashTest{
+		{"text chunk", pngImage(header, pngChunk("tEXt", "Comment\x00text"), pngChunk("IDAT", "abcdef")), true},
+		{"split image data", pngImage(header, pngChunk("IDAT", "abc"), pngChunk("IDAT", "def")), true},
+		{"ancillary chunk inside image data", pngImage(header, pngChunk("IDAT", "abc"), pngChunk("tIME", "time"), pngChunk("IDAT", "def")), true},
+		{"trailing data", append(pngImage(header, pngChunk("IDAT", "abcdef")), "trailer"...), true},
+		{"different header", pngImage(pngChunk("IHDR", "other!"), pngChunk("IDAT", "abcdef")), false},
+		{"different image data", pngImage(header, pngChunk("IDAT", "abcdeg")), false},
+		{"palette", pngImage(header, pngChunk("PLTE", "rgb"), pngChunk("IDAT", "abcdef")), false},
+		{"transparency", pngImage(header, pngChunk("tRNS", "\x00\x00"), pngChunk("IDAT", "abcdef")), false},
+	}
+	testImageHasher(t, plain, tests)
+}
+
+func testImageHasher(t *testing.T, plain []byte, tests []imageHashTest) {
+	t.Helper()
+	h := NewImageHasher()
+	want, err := h.Hash(bytes.NewReader(plain))
+	if err != nil {
+		t.Fatalf("Hash() of plain image failed: %v", err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := h.Hash(bytes.NewReader(test.image))
+			if err != nil {
+				t.Fatalf("Hash() failed: %v", err)
+			}
+			if bytes.Equal(got, want) != test.same {
+				t.Errorf("Hash() = %x, plain image hashes to %x, want same = %v", got, want, test.same)
+			}
+		})
+	}
+}
+
+func TestImageHasherRejectsInvalidImages(t *testing.T) {
+	jpeg := jpegImage("data", jpegSegment(0xc0, "frame"))
+	png := pngImage(pngChunk("IHDR", "header"), pngChunk("IDAT", "data"))
+	tests := []struct {
+		name  string
+		image []byte
+	}{
+		{"empty", nil},
+		{"unsupported format", []byte("GIF89a")},
+		{"truncated JPEG", jpeg[:len(jpeg)-2]},
+		{"JPEG segment too short", []byte{0xff, jpegMarkerSOI, 0xff, 0xdb, 0x00, 0x01}},
+		{"JPEG garbage between segments", concat([]byte{0xff, jpegMarkerSOI}, jpegSegment(0xdb, "q"), []byte("x"))},
+		{"PNG without IEND", png[:len(png)-12]},
+		{"PNG chunk beyond end", png[:len(pngSignature)+10]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewImageHasher().Hash(bytes.NewReader(test.image))
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Errorf("Hash() error = %v, want a *FormatError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package hasher
+
+import (
+	"errors"
+	"io"
+)
+
+// IntegrityFailure classifies why content failed an integrity check.
+type IntegrityFailure string
+
+const (
+	// IntegrityChecksumMismatch means the decoded content does not match the
+	// checksum embedded in the file.
+	IntegrityChecksumMismatch IntegrityFailure = "checksum-mismatch"
+	// IntegrityCorruptFrame means a frame failed its CRC check or could not
+	// be decoded.
+	IntegrityCorruptFrame IntegrityFailure = "corrupt-frame"
+	// IntegrityLengthMismatch means the content holds more than the length
+	// recorded in the file.
+	IntegrityLengthMismatch IntegrityFailure = "length-mismatch"
+	// IntegrityTruncated means the content ended unexpectedly.
+	IntegrityTruncated IntegrityFailure = "truncated"
+)
+
+// IntegrityError is returned by hashers checking integrity when the content
+// is corrupt, as opposed to unreadable or of the wrong format.
+type IntegrityError struct {
+	// Failure classifies the corruption.
+	Failure IntegrityFailure
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *IntegrityError) Error() string {
+	return string(e.Failure) + ": " + e.Err.Error()
+}
+
+func (e *IntegrityError) Unwrap() error {
+	return e.Err
+}
+
+// FormatError is returned by hashers parsing a file format when the content
+// is not valid in that format, as opposed to unreadable. Such files cannot
+// be hashed by the hasher, but do not prevent hashing other files.
+type FormatError struct {
+	// Format names the expected format, such as "MP3".
+	Format string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *FormatError) Error() string {
+	return "invalid " + e.Format + " data: " + e.Err.Error()
+}
+
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// formatError wraps err, returned while parsing content in format read
+// through reads, in a FormatError unless a read failed. A nil err is
+// returned unchanged.
+func formatError(format string, err error, reads *readErrorRecorder) error {
+	if err == nil || reads.err != nil {
+		return err
+	}
+	return &FormatError{Format: format, Err: err}
+}
+
+// readErrorRecorder passes reads through to a reader, remembering the first
+// error other than io.EOF, so that decoding errors caused by failing reads
+// can be told apart from decoding errors caused by corrupt content.
+type readErrorRecorder struct {
+	r   io.Reader
+	err error
+}
+
+func (r *readErrorRecorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// ReadAt passes the read through to the reader, which must be an
+// io.ReaderAt, remembering errors like Read.
+func (r *readErrorRecorder) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.r.(io.ReaderAt).ReadAt(p, off)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// classifyHeaderError wraps err, returned while parsing the header of a
+// stream, in an IntegrityError if the stream ended unexpectedly. Other
+// header errors mean the content is not of the expected format and are
+// returned unchanged.
+func classifyHeaderError(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return &IntegrityError{Failure: IntegrityTruncated, Err: err}
+	}
+	return err
+}
+
+// classifyFrameError wraps err, returned while decoding a frame of a stream
+// read through reads, in an IntegrityError unless a read failed.
+func classifyFrameError(err error, reads *readErrorRecorder) error {
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &IntegrityError{Failure: IntegrityTruncated, Err: err}
+	case reads.err != nil:
+		return err
+	default:
+		return &IntegrityError{Failure: IntegrityCorruptFrame, Err: err}
+	}
+}
@@ -0,0 +1,159 @@
+package hasher
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/zeebo/xxh3"
+)
+
+const (
+	// MinHashPermutations is the number of hash functions, and thus values,
+	// in a MinHash signature.
+	MinHashPermutations = 128
+	// shingleSize is the number of consecutive words forming a shingle.
+	shingleSize = 3
+	// minHashSeed seeds the derivation of the MinHash hash functions.
+	minHashSeed = 0x6d696e68617368
+)
+
+// minHashSeeds holds the seed of each MinHash hash function.
+var minHashSeeds = func() [MinHashPermutations]uint64 {
+	var seeds [MinHashPermutations]uint64
+	state := uint64(minHashSeed)
+	for i := range seeds {
+		state = mix64(state)
+		seeds[i] = state
+	}
+	return seeds
+}()
+
+func init() {
+	Register(Registration{
+		Name:        "minhash",
+		Description: "Hash text by MinHash signature of its word shingles, for near-duplicate detection",
+		New:         NewMinHashHasher,
+	})
+}
+
+// minHashHasher computes MinHash signatures of text.
+type minHashHasher struct{}
+
+// NewMinHashHasher returns a SimilarityHasher that computes MinHash
+// signatures of text.
+//
+// The text is split into lowercase words of letters and digits, and every
+// run of three consecutive words forms a shingle. The signature records,
+// for each of MinHashPermutations hash functions, the smallest hash of any
+// shingle, so the fraction of matching values in two signatures estimates
+// the Jaccard similarity of the documents' shingle sets. Text is processed
+// as a stream and never held in memory. The algorithm selected by options
+// is ignored; hashes are always reported as MinHash. Returns a *FormatError
+// if the text contains no words.
+func NewMinHashHasher(_ ...Option) Hasher {
+	return &minHashHasher{}
+}
+
+func (h *minHashHasher) Hash(r io.Reader) ([]byte, error) {
+	var signature [MinHashPermutations]uint32
+	for i := range signature {
+		signature[i] = ^uint32(0)
+	}
+	addShingle := func(shingle uint64) {
+		for i, seed := range minHashSeeds {
+			signature[i] = min(signature[i], uint32(mix64(shingle^seed)>>32))
+		}
+	}
+
+	var window []uint64
+	var shingles int
+	addWord := func(word string) {
+		window = append(window, xxh3.HashString(word))
+		if len(window) > shingleSize {
+			window = window[1:]
+		}
+		if len(window) == shingleSize {
+			addShingle(shingleHash(window))
+			shingles++
+		}
+	}
+
+	reader := bufio.NewReader(r)
+	var word strings.Builder
+	for {
+		c, _, err := reader.ReadRune()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			word.WriteRune(unicode.ToLower(c))
+			continue
+		}
+		if word.Len() > 0 {
+			addWord(word.String())
+			word.Reset()
+		}
+	}
+	if word.Len() > 0 {
+		addWord(word.String())
+	}
+	// Texts without words have no shingles to estimate similarity from.
+	if len(window) == 0 {
+		return nil, &FormatError{Format: "text", Err: errors.New("no words to compare")}
+	}
+	// Texts shorter than a shingle still get a signature of their words.
+	if shingles == 0 {
+		addShingle(shingleHash(window))
+	}
+
+	hash := make([]byte, 0, 4*MinHashPermutations)
+	for _, value := range signature {
+		hash = binary.BigEndian.AppendUint32(hash, value)
+	}
+	return hash, nil
+}
+
+func (h *minHashHasher) Similarity(a, b []byte) (float64, error) {
+	if len(a) != 4*MinHashPermutations || len(b) != 4*MinHashPermutations {
+		return 0, errors.New("not a MinHash signature")
+	}
+	matches := 0
+	for i := 0; i < len(a); i += 4 {
+		if binary.BigEndian.Uint32(a[i:]) == binary.BigEndian.Uint32(b[i:]) {
+			matches++
+		}
+	}
+	return float64(matches) / MinHashPermutations, nil
+}
+
+func (h *minHashHasher) Algorithm() Algorithm {
+	return MinHash
+}
+
+func (h *minHashHasher) Name() string {
+	return "minhash"
+}
+
+// shingleHash combines the hashes of the words of a shingle, in order.
+func shingleHash(words []uint64) uint64 {
+	var hash uint64
+	for _, word := range words {
+		hash = mix64(hash ^ word)
+	}
+	return hash
+}
+
+// mix64 computes one step of SplitMix64, a fast bijective mixing function.
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
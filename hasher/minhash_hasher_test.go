@@ -0,0 +1,100 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numberedWords returns a text of n distinct words, replacing the word at
+// index changed, if any, with another one.
+func numberedWords(n, changed int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = fmt.Sprintf("word%d", i)
+	}
+	if changed >= 0 {
+		words[changed] = "changed"
+	}
+	return strings.Join(words, " ")
+}
+
+func TestMinHashHasherSimilarity(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		min, max float64
+	}{
+		{"identical", numberedWords(50, -1), numberedWords(50, -1), 1, 1},
+		{"case and punctuation", "The quick brown fox jumps.", "the QUICK, brown\n  fox -- jumps", 1, 1},
+		{"one word changed", numberedWords(50, -1), numberedWords(50, 25), 0.7, 0.99},
+		{"unrelated", numberedWords(50, -1), strings.Repeat("lorem ipsum dolor sit amet ", 10), 0, 0.1},
+		{"short identical", "hello world", "Hello, world!", 1, 1},
+		{"short reordered", "hello world", "world hello", 0, 0.1},
+		{"accented letters", "café crème brûlée", "CAFÉ CRÈME BRÛLÉE", 1, 1},
+	}
+	h := NewMinHashHasher().(SimilarityHasher)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := h.Hash(strings.NewReader(test.a))
+			if err != nil {
+				t.Fatalf("Hash(%q) failed: %v", test.a, err)
+			}
+			b, err := h.Hash(strings.NewReader(test.b))
+			if err != nil {
+				t.Fatalf("Hash(%q) failed: %v", test.b, err)
+			}
+			if len(a) != 4*MinHashPermutations {
+				t.Fatalf("Hash() returned %d bytes, want %d", len(a), 4*MinHashPermutations)
+			}
+			similarity, err := h.Similarity(a, b)
+			if err != nil {
+				t.Fatalf("Similarity() failed: %v", err)
+			}
+			if similarity < test.min || similarity > test.max {
+				t.Errorf("Similarity() = %v, want between %v and %v", similarity, test.min, test.max)
+			}
+		})
+	}
+}
+
+func TestMinHashHasherRejectsTextWithoutWords(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", ""},
+		{"whitespace", " \t\n\n "},
+		{"punctuation", "... --- !!! ???"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewMinHashHasher().Hash(strings.NewReader(test.text))
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Errorf("Hash(%q) error = %v, want a *FormatError", test.text, err)
+			}
+		})
+	}
+}
+
+func TestMinHashSimilarityRejectsOtherHashes(t *testing.T) {
+	signature := make([]byte, 4*MinHashPermutations)
+	tests := []struct {
+		name string
+		a, b []byte
+	}{
+		{"short first hash", signature[:32], signature},
+		{"short second hash", signature, signature[:32]},
+		{"empty hashes", nil, nil},
+	}
+	h := NewMinHashHasher().(SimilarityHasher)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := h.Similarity(test.a, test.b); err == nil {
+				t.Errorf("Similarity() of %d and %d byte hashes succeeded, want an error", len(test.a), len(test.b))
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package hasher
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+const (
+	// mp3BufferSize must hold the largest MPEG audio frame plus the header
+	// of the frame following it.
+	mp3BufferSize = 8192
+	// id3v1Size is the size of an ID3v1 tag.
+	id3v1Size = 128
+	// apeTagFooterSize is the size of an APEv2 tag header or footer.
+	apeTagFooterSize = 32
+)
+
+var (
+	// mp3Bitrates holds bitrates in kbit/s, indexed by [MPEG-1][layer-1][index].
+	mp3Bitrates = [2][3][15]int{
+		{ // MPEG-2 and MPEG-2.5
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		},
+		{ // MPEG-1
+			{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+			{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		},
+	}
+	// mp3SampleRates holds sample rates in Hz, indexed by [version][index]
+	// with versions numbered as in the frame header.
+	mp3SampleRates = [4][3]int{
+		{11025, 12000, 8000},  // MPEG-2.5
+		{},                    // reserved
+		{22050, 24000, 16000}, // MPEG-2
+		{44100, 48000, 32000}, // MPEG-1
+	}
+)
+
+func init() {
+	Register(Registration{
+		Name:        "mp3",
+		Description: "Hash MPEG audio frames, ignoring ID3, APE and Xing/LAME tags",
+		New:         NewMp3Hasher,
+	})
+}
+
+// mp3Hasher computes hashes of the MPEG audio frames in an MP3 file.
+type mp3Hasher struct {
+	algorithm Algorithm
+}
+
+// NewMp3Hasher returns a Hasher that hashes the MPEG audio frames of MP3
+// files with the algorithm selected by options, SHA-256 by default.
+//
+// Leading ID3v2 tags, trailing ID3v1 and APEv2 tags and the Xing, Info or
+// VBRI frame written by encoders are skipped without decoding the audio, so
+// copies of the same MP3 differing only in tags produce the same hash.
+// Returns a *FormatError if the input contains no MPEG audio frames.
+func NewMp3Hasher(options ...Option) Hasher {
+	c := newConfig(options)
+	return &mp3Hasher{algorithm: c.algorithm}
+}
+
+func (h *mp3Hasher) Hash(r io.Reader) ([]byte, error) {
+	reads := &readErrorRecorder{r: r}
+	sum, err := h.hashFrames(reads)
+	if err != nil {
+		return nil, formatError("MP3", err, reads)
+	}
+	return sum, nil
+}
+
+// hashFrames hashes the MPEG audio frames in r.
+func (h *mp3Hasher) hashFrames(r io.Reader) ([]byte, error) {
+	reader := bufio.NewReaderSize(r, mp3BufferSize)
+	if err := skipID3v2Tags(reader); err != nil {
+		return nil, err
+	}
+
+	hash := h.algorithm.New()
+	frames := 0
+	synced := false
+	for {
+		header, err := reader.Peek(4)
+		if len(header) < 4 {
+			if err == io.EOF || err == nil {
+				break
+			}
+			return nil, err
+		}
+
+		skip, err := mp3TagLength(reader, header)
+		if err != nil {
+			return nil, err
+		}
+		if skip > 0 {
+			if _, err := reader.Discard(skip); err != nil && err != io.EOF {
+				return nil, err
+			}
+			synced = false
+			continue
+		}
+
+		frame, ok := peekMp3Frame(reader, header, !synced)
+		if !ok {
+			// Not a frame boundary; resynchronize byte by byte.
+			if _, err := reader.Discard(1); err != nil {
+				return nil, err
+			}
+			synced = false
+			continue
+		}
+		synced = true
+		if frames > 0 || !isMp3InfoFrame(frame) {
+			_, _ = hash.Write(frame)
+		}
+		frames++
+		if _, err := reader.Discard(len(frame)); err != nil {
+			return nil, err
+		}
+	}
+
+	if frames == 0 {
+		return nil, errors.New("no MPEG audio frames found")
+	}
+	return hash.Sum(nil), nil
+}
+
+func (h *mp3Hasher) Algorithm() Algorithm {
+	return h.algorithm
+}
+
+func (h *mp3Hasher) Name() string {
+	return "mp3"
+}
+
+// skipID3v2Tags discards the ID3v2 tags at the start of reader.
+func skipID3v2Tags(reader *bufio.Reader) error {
+	for {
+		header, _ := reader.Peek(10)
+		size, ok := id3v2TagLength(header)
+		if !ok {
+			return nil
+		}
+		if _, err := reader.Discard(size); err != nil {
+			return err
+		}
+	}
+}
+
+// id3v2TagLength returns the total length of the ID3v2 tag whose 10-byte
+// header is given, including the header and an optional footer.
+func id3v2TagLength(header []byte) (int, bool) {
+	if len(header) < 10 || !bytes.HasPrefix(header, []byte("ID3")) {
+		return 0, false
+	}
+	size := 0
+	for _, b := range header[6:10] {
+		if b&0x80 != 0 {
+			return 0, false
+		}
+		size = size<<7 | int(b)
+	}
+	size += 10
+	if header[5]&0x10 != 0 {
+		size += 10
+	}
+	return size, true
+}
+
+// mp3TagLength returns the length of the tag starting at the front of
+// reader, given its first four bytes, or zero if no tag starts there.
+func mp3TagLength(reader *bufio.Reader, header []byte) (int, error) {
+	switch {
+	case bytes.HasPrefix(header, []byte("TAG")):
+		return id3v1Size, nil
+	case bytes.HasPrefix(header, []byte("ID3")):
+		tag, _ := reader.Peek(10)
+		if size, ok := id3v2TagLength(tag); ok {
+			return size, nil
+		}
+	case bytes.HasPrefix(header, []byte("APET")):
+		tag, _ := reader.Peek(apeTagFooterSize)
+		if size, ok := apeTagLength(tag); ok {
+			return size, nil
+		}
+	}
+	return 0, nil
+}
+
+// apeTagLength returns the length of the APEv2 tag starting with the given
+// header or footer. A header is followed by the items and a footer, whereas
+// the items precede a footer.
+func apeTagLength(tag []byte) (int, bool) {
+	if len(tag) < apeTagFooterSize || !bytes.HasPrefix(tag, []byte("APETAGEX")) {
+		return 0, false
+	}
+	size := int(uint32(tag[12]) | uint32(tag[13])<<8 | uint32(tag[14])<<16 | uint32(tag[15])<<24)
+	if tag[23]&0x20 != 0 {
+		return apeTagFooterSize + size, true
+	}
+	return apeTagFooterSize, true
+}
+
+// peekMp3Frame returns the MPEG audio frame at the front of reader, given
+// its first four bytes. When validate is set, as it is while searching for
+// frame sync, a frame is only accepted if it is followed by another frame,
+// a tag or the end of the stream, which rules out most false frame syncs.
+func peekMp3Frame(reader *bufio.Reader, header []byte, validate bool) ([]byte, bool) {
+	length, ok := mp3FrameLength(header)
+	if !ok {
+		return nil, false
+	}
+
+	data, err := reader.Peek(length + 4)
+	if len(data) < length {
+		return nil, false
+	}
+	if validate && err == nil {
+		next := data[length:]
+		_, nextIsFrame := mp3FrameLength(next)
+		nextIsTag := bytes.HasPrefix(next, []byte("TAG")) ||
+			bytes.HasPrefix(next, []byte("ID3")) ||
+			bytes.HasPrefix(next, []byte("APET"))
+		if !nextIsFrame && !nextIsTag {
+			return nil, false
+		}
+	}
+	return data[:length], true
+}
+
+// mp3FrameLength returns the length of the MPEG audio frame with the given
+// header, or false if header is not a valid frame header.
+func mp3FrameLength(header []byte) (int, bool) {
+	if len(header) < 4 || header[0] != 0xff || header[1]&0xe0 != 0xe0 {
+		return 0, false
+	}
+	version := int(header[1]>>3) & 0x3
+	layer := 4 - int(header[1]>>1)&0x3
+	bitrateIndex := int(header[2] >> 4)
+	sampleRateIndex := int(header[2]>>2) & 0x3
+	padding := int(header[2]>>1) & 0x1
+	if version == 1 || layer == 4 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return 0, false
+	}
+
+	mpeg1 := 0
+	if version == 3 {
+		mpeg1 = 1
+	}
+	bitrate := mp3Bitrates[mpeg1][layer-1][bitrateIndex] * 1000
+	sampleRate := mp3SampleRates[version][sampleRateIndex]
+
+	switch {
+	case layer == 1:
+		return (12*bitrate/sampleRate + padding) * 4, true
+	case layer == 3 && mpeg1 == 0:
+		return 72*bitrate/sampleRate + padding, true
+	default:
+		return 144*bitrate/sampleRate + padding, true
+	}
+}
+
+// isMp3InfoFrame reports whether frame is a Xing, Info or VBRI frame, which
+// encoders write in place of the first audio frame to describe the stream.
+func isMp3InfoFrame(frame []byte) bool {
+	mpeg1 := frame[1]>>3&0x3 == 3
+	mono := frame[3]>>6 == 3
+
+	offset := 4
+	switch {
+	case mpeg1 && mono:
+		offset += 17
+	case mpeg1:
+		offset += 32
+	case mono:
+		offset += 9
+	default:
+		offset += 17
+	}
+	if frame[1]&0x1 == 0 {
+		offset += 2 // CRC
+	}
+
+	hasTag := func(at int, tag string) bool {
+		return len(frame) >= at+len(tag) && string(frame[at:at+len(tag)]) == tag
+	}
+	return hasTag(offset, "Xing") || hasTag(offset, "Info") || hasTag(36, "VBRI")
+}
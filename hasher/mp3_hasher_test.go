@@ -0,0 +1,179 @@
+package hasher
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// mp3Frame returns an MPEG-1 Layer III frame at 128 kbit/s and 44.1 kHz
+// whose audio data is filled with fill.
+func mp3Frame(fill byte) []byte {
+	frame := bytes.Repeat([]byte{fill}, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x44})
+	return frame
+}
+
+// mp3XingFrame returns a frame like mp3Frame carrying a Xing header.
+func mp3XingFrame() []byte {
+	frame := mp3Frame(0)
+	copy(frame[36:], "Xing")
+	return frame
+}
+
+// id3v2Tag returns an ID3v2.4 tag with size bytes of tag data.
+func id3v2Tag(size byte) []byte {
+	tag := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, size}
+	return append(tag, bytes.Repeat([]byte{'x'}, int(size))...)
+}
+
+// apeTag returns an APEv2 tag with a header, items of itemsSize bytes and a
+// footer.
+func apeTag(itemsSize int) []byte {
+	header := func(isHeader bool) []byte {
+		block := make([]byte, apeTagFooterSize)
+		copy(block, "APETAGEX")
+		size := itemsSize + apeTagFooterSize
+		block[12], block[13] = byte(size), byte(size>>8)
+		if isHeader {
+			block[23] = 0xa0
+		}
+		return block
+	}
+	tag := header(true)
+	tag = append(tag, bytes.Repeat([]byte{'i'}, itemsSize)...)
+	return append(tag, header(false)...)
+}
+
+func TestMp3FrameLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		length int
+		ok     bool
+	}{
+		{"MPEG-1 Layer III", []byte{0xff, 0xfb, 0x90, 0x00}, 417, true},
+		{"MPEG-1 Layer III padded", []byte{0xff, 0xfb, 0x92, 0x00}, 418, true},
+		{"MPEG-2 Layer III", []byte{0xff, 0xf3, 0x80, 0x00}, 208, true},
+		{"MPEG-1 Layer I", []byte{0xff, 0xff, 0x90, 0x00}, 312, true},
+		{"free format bitrate", []byte{0xff, 0xfb, 0x00, 0x00}, 0, false},
+		{"invalid bitrate", []byte{0xff, 0xfb, 0xf0, 0x00}, 0, false},
+		{"reserved sample rate", []byte{0xff, 0xfb, 0x9c, 0x00}, 0, false},
+		{"reserved version", []byte{0xff, 0xeb, 0x90, 0x00}, 0, false},
+		{"reserved layer", []byte{0xff, 0xf9, 0x90, 0x00}, 0, false},
+		{"no frame sync", []byte{0xff, 0x7b, 0x90, 0x00}, 0, false},
+		{"short header", []byte{0xff, 0xfb, 0x90}, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			length, ok := mp3FrameLength(test.header)
+			if length != test.length || ok != test.ok {
+				t.Errorf("mp3FrameLength(% x) = %d, %v, want %d, %v", test.header, length, ok, test.length, test.ok)
+			}
+		})
+	}
+}
+
+func TestID3v2TagLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+		length int
+		ok     bool
+	}{
+		{"small tag", []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 20}, 30, true},
+		{"syncsafe size", []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 1, 0}, 138, true},
+		{"with footer", []byte{'I', 'D', '3', 4, 0, 0x10, 0, 0, 0, 20}, 40, true},
+		{"invalid size byte", []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0x80, 0}, 0, false},
+		{"not a tag", []byte{'T', 'A', 'G', 4, 0, 0, 0, 0, 0, 20}, 0, false},
+		{"short header", []byte{'I', 'D', '3', 4, 0}, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			length, ok := id3v2TagLength(test.header)
+			if length != test.length || ok != test.ok {
+				t.Errorf("id3v2TagLength(% x) = %d, %v, want %d, %v", test.header, length, ok, test.length, test.ok)
+			}
+		})
+	}
+}
+
+func TestApeTagLength(t *testing.T) {
+	tag := apeTag(10)
+	tests := []struct {
+		name   string
+		tag    []byte
+		length int
+		ok     bool
+	}{
+		{"header", tag[:apeTagFooterSize], len(tag), true},
+		{"footer", tag[len(tag)-apeTagFooterSize:], apeTagFooterSize, true},
+		{"short", tag[:apeTagFooterSize-1], 0, false},
+		{"not a tag", make([]byte, apeTagFooterSize), 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			length, ok := apeTagLength(test.tag)
+			if length != test.length || ok != test.ok {
+				t.Errorf("apeTagLength() = %d, %v, want %d, %v", length, ok, test.length, test.ok)
+			}
+		})
+	}
+}
+
+func TestMp3HasherIgnoresTags(t *testing.T) {
+	audio := concat(mp3Frame(1), mp3Frame(2), mp3Frame(3))
+	tests := []struct {
+		name    string
+		content []byte
+		same    bool
+	}{
+		{"ID3v2 tag", concat(id3v2Tag(20), audio), true},
+		{"two ID3v2 tags", concat(id3v2Tag(20), id3v2Tag(5), audio), true},
+		{"ID3v1 tag", concat(audio, []byte("TAG"), make([]byte, id3v1Size-3)), true},
+		{"APEv2 tag", concat(audio, apeTag(40)), true},
+		{"APEv2 and ID3v1 tags", concat(audio, apeTag(40), []byte("TAG"), make([]byte, id3v1Size-3)), true},
+		{"Xing frame", concat(mp3XingFrame(), audio), true},
+		{"junk before frames", concat([]byte("junk"), audio), true},
+		{"different audio", concat(mp3Frame(1), mp3Frame(2), mp3Frame(4)), false},
+		{"missing frame", concat(mp3Frame(1), mp3Frame(2)), false},
+	}
+
+	h := NewMp3Hasher()
+	want, err := h.Hash(bytes.NewReader(audio))
+	if err != nil {
+		t.Fatalf("Hash() of plain audio failed: %v", err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := h.Hash(bytes.NewReader(test.content))
+			if err != nil {
+				t.Fatalf("Hash() failed: %v", err)
+			}
+			if bytes.Equal(got, want) != test.same {
+				t.Errorf("Hash() = %x, plain audio hashes to %x, want same = %v", got, want, test.same)
+			}
+		})
+	}
+}
+
+func TestMp3HasherRejectsContentWithoutFrames(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{"empty", nil},
+		{"text", []byte("not an mp3 file")},
+		{"tags only", concat(id3v2Tag(20), []byte("TAG"), make([]byte, id3v1Size-3))},
+		{"truncated frame", mp3Frame(1)[:200]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewMp3Hasher().Hash(bytes.NewReader(test.content))
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Errorf("Hash() error = %v, want a *FormatError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package hasher
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// boxHeaderSize is the size of a compact ISO-BMFF box header.
+	boxHeaderSize = 8
+	// boxLargeSizeLength is the length of the 64-bit size that follows the
+	// header of boxes whose compact size is 1.
+	boxLargeSizeLength = 8
+)
+
+func init() {
+	Register(Registration{
+		Name:        "mp4",
+		Description: "Hash MP4 and QuickTime media data, ignoring moov, udta, meta and free boxes",
+		New:         NewMp4Hasher,
+	})
+}
+
+// mp4Hasher computes hashes of the media data in ISO-BMFF files.
+type mp4Hasher struct {
+	algorithm Algorithm
+}
+
+// NewMp4Hasher returns a Hasher that hashes the media data of MP4, QuickTime
+// and other ISO base media files with the algorithm selected by options,
+// SHA-256 by default.
+//
+// The hasher walks the top-level boxes and hashes only the payloads of mdat
+// boxes, which hold the encoded audio and video samples. The moov box with
+// its sample tables, udta and meta boxes with tags, and free space are
+// skipped, so re-tagging a video or re-muxing it without re-encoding leaves
+// the hash unchanged. Returns a *FormatError if the input contains no media
+// data.
+func NewMp4Hasher(options ...Option) Hasher {
+	c := newConfig(options)
+	return &mp4Hasher{algorithm: c.algorithm}
+}
+
+func (h *mp4Hasher) Hash(r io.Reader) ([]byte, error) {
+	reads := &readErrorRecorder{r: r}
+	sum, err := h.hashMediaData(reads)
+	if err != nil {
+		return nil, formatError("MP4", err, reads)
+	}
+	return sum, nil
+}
+
+// hashMediaData hashes the payloads of the mdat boxes in r.
+func (h *mp4Hasher) hashMediaData(r io.Reader) ([]byte, error) {
+	reader := bufio.NewReader(r)
+	hash := h.algorithm.New()
+	foundMediaData := false
+
+	for {
+		boxType, payloadSize, err := readBoxHeader(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		destination := io.Discard
+		if boxType == "mdat" {
+			destination = hash
+			foundMediaData = true
+		}
+		if payloadSize < 0 {
+			// The box extends to the end of the file.
+			if _, err := io.Copy(destination, reader); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if _, err := io.CopyN(destination, reader, payloadSize); err != nil {
+			return nil, fmt.Errorf("truncated %q box: %w", boxType, unexpectedEOF(err))
+		}
+	}
+
+	if !foundMediaData {
+		return nil, errors.New("no mdat box found")
+	}
+	return hash.Sum(nil), nil
+}
+
+func (h *mp4Hasher) Algorithm() Algorithm {
+	return h.algorithm
+}
+
+func (h *mp4Hasher) Name() string {
+	return "mp4"
+}
+
+// readBoxHeader reads an ISO-BMFF box header and returns the box type and
+// payload size. A negative size means the box extends to the end of the
+// file. Returns io.EOF if there are no more boxes.
+func readBoxHeader(reader *bufio.Reader) (string, int64, error) {
+	var header [boxHeaderSize]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", 0, io.EOF
+		}
+		return "", 0, fmt.Errorf("truncated box header: %w", unexpectedEOF(err))
+	}
+	size := uint64(binary.BigEndian.Uint32(header[0:4]))
+	boxType := string(header[4:8])
+
+	switch size {
+	case 0:
+		return boxType, -1, nil
+	case 1:
+		var largeSize [boxLargeSizeLength]byte
+		if _, err := io.ReadFull(reader, largeSize[:]); err != nil {
+			return "", 0, fmt.Errorf("truncated %q box header: %w", boxType, unexpectedEOF(err))
+		}
+		size = binary.BigEndian.Uint64(largeSize[:])
+		if size < boxHeaderSize+boxLargeSizeLength || size > 1<<62 {
+			return "", 0, fmt.Errorf("invalid size %d of %q box", size, boxType)
+		}
+		return boxType, int64(size - boxHeaderSize - boxLargeSizeLength), nil
+	default:
+		if size < boxHeaderSize {
+			return "", 0, fmt.Errorf("invalid size %d of %q box", size, boxType)
+		}
+		return boxType, int64(size - boxHeaderSize), nil
+	}
+}
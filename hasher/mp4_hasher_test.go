@@ -0,0 +1,129 @@
+package hasher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// mp4Box returns an ISO-BMFF box with a compact header.
+func mp4Box(boxType, payload string) []byte {
+	box := make([]byte, 4, boxHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(box, uint32(boxHeaderSize+len(payload)))
+	box = append(box, boxType...)
+	return append(box, payload...)
+}
+
+// mp4LargeBox returns an ISO-BMFF box with a 64-bit size.
+func mp4LargeBox(boxType, payload string) []byte {
+	box := []byte{0, 0, 0, 1}
+	box = append(box, boxType...)
+	box = binary.BigEndian.AppendUint64(box, uint64(boxHeaderSize+boxLargeSizeLength+len(payload)))
+	return append(box, payload...)
+}
+
+// mp4OpenBox returns an ISO-BMFF box extending to the end of the file.
+func mp4OpenBox(boxType, payload string) []byte {
+	box := append([]byte{0, 0, 0, 0}, boxType...)
+	return append(box, payload...)
+}
+
+func TestReadBoxHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		boxType     string
+		payloadSize int64
+		err         bool
+	}{
+		{"compact", mp4Box("ftyp", "isom"), "ftyp", 4, false},
+		{"empty payload", mp4Box("free", ""), "free", 0, false},
+		{"large size", mp4LargeBox("mdat", "media"), "mdat", 5, false},
+		{"to end of file", mp4OpenBox("mdat", "media"), "mdat", -1, false},
+		{"size below header", []byte{0, 0, 0, 7, 'f', 'r', 'e', 'e'}, "", 0, true},
+		{"large size below header", []byte{0, 0, 0, 1, 'm', 'd', 'a', 't', 0, 0, 0, 0, 0, 0, 0, 15}, "", 0, true},
+		{"large size too big", []byte{0, 0, 0, 1, 'm', 'd', 'a', 't', 0x80, 0, 0, 0, 0, 0, 0, 0}, "", 0, true},
+		{"truncated large size", []byte{0, 0, 0, 1, 'm', 'd', 'a', 't', 0, 0}, "", 0, true},
+		{"truncated header", []byte{0, 0, 0, 8, 'f'}, "", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			boxType, payloadSize, err := readBoxHeader(bufio.NewReader(bytes.NewReader(test.data)))
+			if (err != nil) != test.err {
+				t.Fatalf("readBoxHeader() error = %v, want error = %v", err, test.err)
+			}
+			if boxType != test.boxType || payloadSize != test.payloadSize {
+				t.Errorf("readBoxHeader() = %q, %d, want %q, %d", boxType, payloadSize, test.boxType, test.payloadSize)
+			}
+		})
+	}
+}
+
+func TestReadBoxHeaderAtEnd(t *testing.T) {
+	_, _, err := readBoxHeader(bufio.NewReader(bytes.NewReader(nil)))
+	if err != io.EOF {
+		t.Errorf("readBoxHeader() of empty input error = %v, want io.EOF", err)
+	}
+}
+
+func TestMp4HasherHashesOnlyMediaData(t *testing.T) {
+	ftyp := mp4Box("ftyp", "isom")
+	moov := mp4Box("moov", "sample tables")
+	plain := concat(ftyp, mp4Box("mdat", "frames"), moov)
+
+	tests := []struct {
+		name string
+		file []byte
+		same bool
+	}{
+		{"moov first", concat(ftyp, moov, mp4Box("mdat", "frames")), true},
+		{"different sample tables", concat(ftyp, mp4Box("mdat", "frames"), mp4Box("moov", "other tables")), true},
+		{"tags and free space", concat(ftyp, mp4Box("free", "    "), mp4Box("mdat", "frames"), moov, mp4Box("udta", "title")), true},
+		{"large mdat", concat(ftyp, mp4LargeBox("mdat", "frames"), moov), true},
+		{"mdat to end of file", concat(ftyp, moov, mp4OpenBox("mdat", "frames")), true},
+		{"split mdat", concat(ftyp, mp4Box("mdat", "fra"), mp4Box("mdat", "mes"), moov), true},
+		{"different media data", concat(ftyp, mp4Box("mdat", "frameS"), moov), false},
+	}
+	h := NewMp4Hasher()
+	want, err := h.Hash(bytes.NewReader(plain))
+	if err != nil {
+		t.Fatalf("Hash() of plain file failed: %v", err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := h.Hash(bytes.NewReader(test.file))
+			if err != nil {
+				t.Fatalf("Hash() failed: %v", err)
+			}
+			if bytes.Equal(got, want) != test.same {
+				t.Errorf("Hash() = %x, plain file hashes to %x, want same = %v", got, want, test.same)
+			}
+		})
+	}
+}
+
+func TestMp4HasherRejectsInvalidFiles(t *testing.T) {
+	mdat := mp4Box("mdat", "frames")
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"empty", nil},
+		{"no media data", concat(mp4Box("ftyp", "isom"), mp4Box("moov", "tables"))},
+		{"truncated media data", mdat[:len(mdat)-2]},
+		{"truncated box header", concat(mdat, []byte{0, 0, 0})},
+		{"invalid box size", concat(mdat, []byte{0, 0, 0, 4, 'f', 'r', 'e', 'e'})},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewMp4Hasher().Hash(bytes.NewReader(test.file))
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Errorf("Hash() error = %v, want a *FormatError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package hasher
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// officePropertyParts lists the parts of office documents holding document
+// properties, some of which change whenever a document is saved.
+var officePropertyParts = map[string]bool{
+	// Office Open XML core properties.
+	"docProps/core.xml": true,
+	// Office Open XML application properties.
+	"docProps/app.xml": true,
+	// OpenDocument metadata.
+	"meta.xml": true,
+}
+
+// volatileOfficeProperties lists the elements of document property parts
+// that change whenever a document is saved, even if its content does not.
+var volatileOfficeProperties = map[xml.Name]bool{
+	// Office Open XML core properties: modification date, last author and
+	// revision number.
+	{Space: "http://purl.org/dc/terms/", Local: "modified"}:                                                     true,
+	{Space: "http://schemas.openxmlformats.org/package/2006/metadata/core-properties", Local: "lastModifiedBy"}: true,
+	{Space: "http://schemas.openxmlformats.org/package/2006/metadata/core-properties", Local: "revision"}:       true,
+	// Office Open XML application properties: total editing time.
+	{Space: "http://schemas.openxmlformats.org/officeDocument/2006/extended-properties", Local: "TotalTime"}: true,
+	// OpenDocument metadata: modification date, editing cycles and duration.
+	{Space: "http://purl.org/dc/elements/1.1/", Local: "date"}:                           true,
+	{Space: "urn:oasis:names:tc:opendocument:xmlns:meta:1.0", Local: "editing-cycles"}:   true,
+	{Space: "urn:oasis:names:tc:opendocument:xmlns:meta:1.0", Local: "editing-duration"}: true,
+}
+
+func init() {
+	Register(Registration{
+		Name:        "office",
+		Description: "Hash the unpacked parts of zip-based office documents (docx, xlsx, odt, ...)",
+		New:         NewOfficeHasher,
+	})
+}
+
+// officeHasher computes hashes of the parts of zip-based office documents.
+type officeHasher struct {
+	algorithm      Algorithm
+	ignoreVolatile bool
+}
+
+// NewOfficeHasher returns a Hasher that hashes zip-based office documents,
+// such as Office Open XML (docx, xlsx, pptx) and OpenDocument (odt, ods,
+// odp) files, with the algorithm selected by options, SHA-256 by default.
+//
+// The zip container is opened and the names and uncompressed contents of
+// its parts are hashed in order of name, so timestamps, compression levels
+// and part order in the container make no difference. With
+// WithIgnoreVolatileParts, the document properties that change on every
+// save, such as the modification date, last author, revision number and
+// editing time, are left out of the property parts as well, while the other
+// properties, such as title and creator, are still hashed. Returns a
+// *FormatError if the input is not a zip archive or one of its parts cannot
+// be decoded, so the file is reported as unparseable.
+func NewOfficeHasher(options ...Option) Hasher {
+	c := newConfig(options)
+	return &officeHasher{algorithm: c.algorithm, ignoreVolatile: c.ignoreVolatileParts}
+}
+
+func (h *officeHasher) Hash(r io.Reader) ([]byte, error) {
+	reads := &readErrorRecorder{r: r}
+	content, size, err := readerAt(reads)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(content, size)
+	if err != nil {
+		return nil, formatError("office", fmt.Errorf("failed to open document container: %w", err), reads)
+	}
+
+	parts := make([]*zip.File, 0, len(archive.File))
+	for _, part := range archive.File {
+		if part.FileInfo().IsDir() {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	sort.SliceStable(parts, func(i, j int) bool { return parts[i].Name < parts[j].Name })
+
+	hash := h.algorithm.New()
+	for _, part := range parts {
+		if err := h.hashPart(part, hash); err != nil {
+			return nil, formatError("office", fmt.Errorf("failed to read part %q: %w", part.Name, err), reads)
+		}
+	}
+	return hash.Sum(nil), nil
+}
+
+func (h *officeHasher) Algorithm() Algorithm {
+	return h.algorithm
+}
+
+func (h *officeHasher) Name() string {
+	return "office"
+}
+
+// hashPart writes the length-prefixed name and content of part to w. When
+// ignoring volatile properties, the content of property parts is replaced
+// by their remaining properties.
+func (h *officeHasher) hashPart(part *zip.File, w io.Writer) error {
+	content, err := part.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = content.Close() }()
+
+	var r io.Reader = content
+	size := part.UncompressedSize64
+	if h.ignoreVolatile && officePropertyParts[part.Name] {
+		properties, err := stripVolatileProperties(content)
+		if err != nil {
+			return err
+		}
+		r, size = bytes.NewReader(properties), uint64(len(properties))
+	}
+
+	var header []byte
+	header = binary.BigEndian.AppendUint64(header, uint64(len(part.Name)))
+	header = append(header, part.Name...)
+	header = binary.BigEndian.AppendUint64(header, size)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// stripVolatileProperties returns a canonical form of the XML document
+// property part in r without the elements in volatileOfficeProperties.
+// Elements are identified by namespace rather than prefix, so documents
+// using other prefixes for the same namespaces are handled alike.
+func stripVolatileProperties(r io.Reader) ([]byte, error) {
+	decoder := xml.NewDecoder(r)
+	var canonical bytes.Buffer
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return canonical.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if volatileOfficeProperties[t.Name] {
+				if err := decoder.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			fmt.Fprintf(&canonical, "start %q %q\n", t.Name.Space, t.Name.Local)
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					// Namespace declarations only bind prefixes.
+					continue
+				}
+				fmt.Fprintf(&canonical, "attr %q %q %q\n", attr.Name.Space, attr.Name.Local, attr.Value)
+			}
+		case xml.EndElement:
+			fmt.Fprintf(&canonical, "end %q %q\n", t.Name.Space, t.Name.Local)
+		case xml.CharData:
+			fmt.Fprintf(&canonical, "text %q\n", t)
+		case xml.ProcInst:
+			fmt.Fprintf(&canonical, "procinst %q %q\n", t.Target, t.Inst)
+		}
+	}
+}
+
+// readerAt returns the content read through reads as an io.ReaderAt along
+// with its size. Files are used in place, while other readers are read into
+// memory.
+func readerAt(reads *readErrorRecorder) (io.ReaderAt, int64, error) {
+	if file, ok := reads.r.(interface {
+		io.ReaderAt
+		io.Seeker
+	}); ok {
+		if size, err := file.Seek(0, io.SeekEnd); err == nil {
+			return reads, size, nil
+		}
+	}
+	content, err := io.ReadAll(reads)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(content), int64(len(content)), nil
+}
@@ -0,0 +1,141 @@
+package hasher
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// coreProperties returns an Office Open XML core properties part with the
+// given title, modification date and last author.
+func coreProperties(title, modified, lastModifiedBy string) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"` +
+		` xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/">` +
+		`<dc:title>` + title + `</dc:title>` +
+		`<cp:lastModifiedBy>` + lastModifiedBy + `</cp:lastModifiedBy>` +
+		`<dcterms:modified>` + modified + `</dcterms:modified>` +
+		`</cp:coreProperties>`
+}
+
+// officeDocument returns a zip-based document holding the given parts,
+// alternating names and contents, stored in order.
+func officeDocument(t *testing.T, parts ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for i := 0; i < len(parts); i += 2 {
+		w, err := archive.Create(parts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, parts[i+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOfficeHasherIgnoresVolatileProperties(t *testing.T) {
+	const body = "word/document.xml"
+	original := officeDocument(t,
+		"docProps/core.xml", coreProperties("Report", "2024-01-01T10:00:00Z", "alice"),
+		body, "<w:document>content</w:document>")
+
+	tests := []struct {
+		name     string
+		document []byte
+		same     bool
+	}{
+		{"same parts in another order", officeDocument(t,
+			body, "<w:document>content</w:document>",
+			"docProps/core.xml", coreProperties("Report", "2024-01-01T10:00:00Z", "alice")), true},
+		{"modified and last author", officeDocument(t,
+			"docProps/core.xml", coreProperties("Report", "2025-06-30T18:30:00Z", "bob"),
+			body, "<w:document>content</w:document>"), true},
+		{"title", officeDocument(t,
+			"docProps/core.xml", coreProperties("Draft", "2024-01-01T10:00:00Z", "alice"),
+			body, "<w:document>content</w:document>"), false},
+		{"content", officeDocument(t,
+			"docProps/core.xml", coreProperties("Report", "2025-06-30T18:30:00Z", "bob"),
+			body, "<w:document>other content</w:document>"), false},
+	}
+	h := NewOfficeHasher(WithIgnoreVolatileParts())
+	want, err := h.Hash(bytes.NewReader(original))
+	if err != nil {
+		t.Fatalf("Hash() of original document failed: %v", err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := h.Hash(bytes.NewReader(test.document))
+			if err != nil {
+				t.Fatalf("Hash() failed: %v", err)
+			}
+			if bytes.Equal(got, want) != test.same {
+				t.Errorf("Hash() = %x, original document hashes to %x, want same = %v", got, want, test.same)
+			}
+		})
+	}
+}
+
+func TestOfficeHasherKeepsVolatilePropertiesByDefault(t *testing.T) {
+	a := officeDocument(t, "docProps/core.xml", coreProperties("Report", "2024-01-01T10:00:00Z", "alice"))
+	b := officeDocument(t, "docProps/core.xml", coreProperties("Report", "2025-06-30T18:30:00Z", "bob"))
+	h := NewOfficeHasher()
+	hashA, err := h.Hash(bytes.NewReader(a))
+	if err != nil {
+		t.Fatalf("Hash() of first document failed: %v", err)
+	}
+	hashB, err := h.Hash(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Hash() of second document failed: %v", err)
+	}
+	if bytes.Equal(hashA, hashB) {
+		t.Errorf("Hash() = %x for both documents, want different hashes", hashA)
+	}
+}
+
+func TestOfficeHasherRejectsInvalidDocuments(t *testing.T) {
+	document := officeDocument(t, "word/document.xml", "<w:document>content</w:document>")
+	tests := []struct {
+		name     string
+		document []byte
+	}{
+		{"empty", nil},
+		{"not a zip archive", []byte("plain text, not a document")},
+		{"truncated zip archive", document[:len(document)-10]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewOfficeHasher().Hash(bytes.NewReader(test.document))
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Errorf("Hash() error = %v, want a *FormatError", err)
+			}
+		})
+	}
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "document.docx")
+		if err := os.WriteFile(path, []byte("plain text, not a document"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = file.Close() }()
+		_, err = NewOfficeHasher().Hash(file)
+		var formatErr *FormatError
+		if !errors.As(err, &formatErr) {
+			t.Errorf("Hash() error = %v, want a *FormatError", err)
+		}
+	})
+}
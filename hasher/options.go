@@ -0,0 +1,81 @@
+package hasher
+
+// Option configures optional behavior of a Hasher.
+//
+// Hashers ignore options that do not apply to them.
+type Option func(*config)
+
+// config holds the settings shared by all hashers.
+type config struct {
+	algorithm           Algorithm
+	streamInfoMD5       bool
+	verify              bool
+	integrityCheck      bool
+	textNormalization   TextNormalization
+	ignoreVolatileParts bool
+}
+
+// newConfig returns the configuration resulting from applying options to
+// the defaults.
+func newConfig(options []Option) config {
+	c := config{
+		algorithm:         SHA256,
+		textNormalization: AllTextNormalizations,
+	}
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// WithAlgorithm selects the algorithm used to digest content.
+// The default is SHA256; algorithm must be one of Algorithms.
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(c *config) {
+		c.algorithm = algorithm
+	}
+}
+
+// WithStreamInfoMD5 makes the FLAC hasher use the MD5 signature embedded in
+// the STREAMINFO block as the hash instead of decoding the audio. Files
+// without a signature are decoded and hashed with MD5, so all hashes remain
+// comparable. The selected algorithm is ignored.
+func WithStreamInfoMD5() Option {
+	return func(c *config) {
+		c.streamInfoMD5 = true
+	}
+}
+
+// WithVerify makes hashers that trust embedded checksums decode the content
+// anyway and check it against the checksum. It implies WithIntegrityCheck.
+func WithVerify() Option {
+	return func(c *config) {
+		c.verify = true
+		c.integrityCheck = true
+	}
+}
+
+// WithIntegrityCheck makes hashers that decode content check it against
+// embedded checksums. Corrupt content is reported as an *IntegrityError.
+func WithIntegrityCheck() Option {
+	return func(c *config) {
+		c.integrityCheck = true
+	}
+}
+
+// WithTextNormalization selects the differences the text hasher ignores.
+// The default is AllTextNormalizations; zero hashes text unchanged.
+func WithTextNormalization(normalization TextNormalization) Option {
+	return func(c *config) {
+		c.textNormalization = normalization
+	}
+}
+
+// WithIgnoreVolatileParts makes the office hasher skip the document
+// properties that change whenever a document is saved, such as the
+// modification date in docProps/core.xml, while still hashing the others.
+func WithIgnoreVolatileParts() Option {
+	return func(c *config) {
+		c.ignoreVolatileParts = true
+	}
+}
@@ -0,0 +1,126 @@
+package hasher
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// pcmReadFrames is the number of sample frames decoded per read.
+const pcmReadFrames = 4096
+
+// sampleLayout describes how integer samples are stored in a container.
+type sampleLayout struct {
+	// bytesPerSample is the size of each sample's container.
+	bytesPerSample int
+	// validBits is the number of significant, left-justified bits.
+	validBits int
+	// bigEndian selects the byte order of each sample.
+	bigEndian bool
+	// unsigned marks samples stored with an offset of half their range.
+	unsigned bool
+}
+
+// newSampleLayout validates the sample layout of an uncompressed container.
+func newSampleLayout(containerBits, validBits int, bigEndian, unsigned bool) (sampleLayout, error) {
+	bytesPerSample := (containerBits + 7) / 8
+	if bytesPerSample < 1 || bytesPerSample > 4 || validBits < 1 || validBits > bytesPerSample*8 {
+		return sampleLayout{}, fmt.Errorf("unsupported sample size of %d bits", containerBits)
+	}
+	return sampleLayout{bytesPerSample, validBits, bigEndian, unsigned}, nil
+}
+
+// decode converts the whole samples in data to canonical values, appending
+// them to samples.
+func (l sampleLayout) decode(data []byte, samples []int32) []int32 {
+	width := l.bytesPerSample * 8
+	for offset := 0; offset+l.bytesPerSample <= len(data); offset += l.bytesPerSample {
+		raw := data[offset : offset+l.bytesPerSample]
+		var value uint32
+		if l.bigEndian {
+			for _, b := range raw {
+				value = value<<8 | uint32(b)
+			}
+		} else {
+			for i := len(raw) - 1; i >= 0; i-- {
+				value = value<<8 | uint32(raw[i])
+			}
+		}
+
+		var sample int32
+		if l.unsigned {
+			sample = int32(int64(value) - 1<<(width-1))
+		} else {
+			sample = int32(value<<(32-width)) >> (32 - width)
+		}
+		samples = append(samples, sample>>(width-l.validBits))
+	}
+	return samples
+}
+
+// decodePCMData streams the size bytes of interleaved samples in r to w. A
+// trailing partial sample frame is ignored, as writers commonly leave one
+// behind, but data ending before size bytes is reported as truncated.
+func decodePCMData(r io.Reader, size int64, layout sampleLayout, channels int, w PCMWriter) error {
+	frameBytes := layout.bytesPerSample * channels
+	buffer := make([]byte, frameBytes*pcmReadFrames)
+	data := io.LimitReader(r, size)
+	var samples []int32
+	var read int64
+
+	for {
+		n, err := io.ReadFull(data, buffer)
+		read += int64(n)
+		n -= n % frameBytes
+		if n > 0 {
+			samples = layout.decode(buffer[:n], samples[:0])
+			if writeErr := w.WriteSamples(samples); writeErr != nil {
+				return writeErr
+			}
+		}
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			if read < size {
+				return fmt.Errorf("audio data ends after %d of %d bytes: %w", read, size, io.ErrUnexpectedEOF)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// chunkHeader is the header of a RIFF or IFF chunk.
+type chunkHeader struct {
+	id   string
+	size uint32
+}
+
+// readChunkHeader reads a chunk header using the given byte order.
+// Returns io.EOF if r holds no further chunks.
+func readChunkHeader(r io.Reader, order binary.ByteOrder) (chunkHeader, error) {
+	var raw [8]byte
+	if _, err := io.ReadFull(r, raw[:]); err != nil {
+		return chunkHeader{}, err
+	}
+	return chunkHeader{id: string(raw[:4]), size: order.Uint32(raw[4:])}, nil
+}
+
+// skipChunk discards the remaining size bytes of a chunk and its padding.
+func skipChunk(r io.Reader, size uint32) error {
+	_, err := io.CopyN(io.Discard, r, int64(size)+int64(size&1))
+	return err
+}
+
+// extendedToUint32 converts an 80-bit IEEE 754 extended precision number,
+// as used for AIFF sample rates, to an integer.
+func extendedToUint32(raw [10]byte) uint32 {
+	exponent := int(binary.BigEndian.Uint16(raw[0:])&0x7fff) - 16383
+	mantissa := binary.BigEndian.Uint64(raw[2:])
+	if raw[0]&0x80 != 0 || exponent < 0 || exponent > 31 {
+		return 0
+	}
+	return uint32(math.Round(float64(mantissa) / math.Pow(2, float64(63-exponent))))
+}
@@ -0,0 +1,84 @@
+package hasher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewSampleLayout(t *testing.T) {
+	tests := []struct {
+		name          string
+		containerBits int
+		validBits     int
+		layout        sampleLayout
+		ok            bool
+	}{
+		{"8-bit", 8, 8, sampleLayout{bytesPerSample: 1, validBits: 8}, true},
+		{"12-bit padded to 16", 12, 12, sampleLayout{bytesPerSample: 2, validBits: 12}, true},
+		{"24-bit in 32-bit container", 32, 24, sampleLayout{bytesPerSample: 4, validBits: 24}, true},
+		{"no bits", 0, 0, sampleLayout{}, false},
+		{"too wide", 40, 40, sampleLayout{}, false},
+		{"more valid bits than container", 16, 24, sampleLayout{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			layout, err := newSampleLayout(test.containerBits, test.validBits, false, false)
+			if (err == nil) != test.ok {
+				t.Fatalf("newSampleLayout(%d, %d) error = %v, want ok = %v", test.containerBits, test.validBits, err, test.ok)
+			}
+			if layout != test.layout {
+				t.Errorf("newSampleLayout(%d, %d) = %+v, want %+v", test.containerBits, test.validBits, layout, test.layout)
+			}
+		})
+	}
+}
+
+func TestSampleLayoutDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		layout  sampleLayout
+		data    []byte
+		samples []int32
+	}{
+		{"8-bit unsigned", sampleLayout{1, 8, false, true}, []byte{0x00, 0x80, 0xff}, []int32{-128, 0, 127}},
+		{"8-bit signed", sampleLayout{1, 8, true, false}, []byte{0x80, 0x00, 0x7f}, []int32{-128, 0, 127}},
+		{"16-bit little-endian", sampleLayout{2, 16, false, false}, []byte{0x01, 0x00, 0xff, 0xff, 0x00, 0x80}, []int32{1, -1, -32768}},
+		{"16-bit big-endian", sampleLayout{2, 16, true, false}, []byte{0x00, 0x01, 0xff, 0xff, 0x80, 0x00}, []int32{1, -1, -32768}},
+		{"24-bit little-endian", sampleLayout{3, 24, false, false}, []byte{0xff, 0xff, 0x7f, 0x00, 0x00, 0x80}, []int32{8388607, -8388608}},
+		{"24-bit in 32-bit container", sampleLayout{4, 24, false, false}, []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff}, []int32{1, -1}},
+		{"12-bit left-justified", sampleLayout{2, 12, true, false}, []byte{0x00, 0x10, 0xff, 0xf0}, []int32{1, -1}},
+		{"trailing partial sample", sampleLayout{2, 16, false, false}, []byte{0x02, 0x00, 0x03}, []int32{2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			samples := test.layout.decode(test.data, nil)
+			if !reflect.DeepEqual(samples, test.samples) {
+				t.Errorf("decode(% x) = %v, want %v", test.data, samples, test.samples)
+			}
+		})
+	}
+}
+
+func TestExtendedToUint32(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   [10]byte
+		value uint32
+	}{
+		{"44100", [10]byte{0x40, 0x0e, 0xac, 0x44}, 44100},
+		{"48000", [10]byte{0x40, 0x0e, 0xbb, 0x80}, 48000},
+		{"8000", [10]byte{0x40, 0x0b, 0xfa}, 8000},
+		{"one", [10]byte{0x3f, 0xff, 0x80}, 1},
+		{"fraction rounds", [10]byte{0x40, 0x00, 0xe0}, 4},
+		{"below one", [10]byte{0x3f, 0xfe, 0x80}, 0},
+		{"negative", [10]byte{0xc0, 0x0e, 0xac, 0x44}, 0},
+		{"too large", [10]byte{0x40, 0x1f, 0x80}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if value := extendedToUint32(test.raw); value != test.value {
+				t.Errorf("extendedToUint32(% x) = %d, want %d", test.raw, value, test.value)
+			}
+		})
+	}
+}
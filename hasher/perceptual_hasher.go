@@ -0,0 +1,143 @@
+package hasher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math/bits"
+)
+
+const (
+	// dHashWidth is the number of horizontal gradients per row of a dHash.
+	dHashWidth = 8
+	// dHashHeight is the number of rows of a dHash.
+	dHashHeight = 8
+	// perceptualMaxPixels is the largest image, in pixels, that is decoded.
+	// Decoding allocates the full image, so a small file declaring huge
+	// dimensions would otherwise exhaust memory.
+	perceptualMaxPixels = 1 << 28
+)
+
+func init() {
+	Register(Registration{
+		Name:        "perceptual",
+		Description: "Hash decoded JPEG, PNG and GIF images by appearance (dHash)",
+		New:         NewPerceptualHasher,
+	})
+}
+
+// perceptualHasher computes difference hashes of decoded images.
+type perceptualHasher struct{}
+
+// NewPerceptualHasher returns a SimilarityHasher that computes a 64-bit difference
+// hash (dHash) of decoded JPEG, PNG and GIF images.
+//
+// The image is reduced to a 9x8 grid of average luminance values, and each
+// bit of the hash records whether brightness increases from one cell to the
+// next within a row. Resized, recompressed or slightly edited copies of an
+// image therefore produce hashes that differ in only a few bits. The
+// algorithm selected by options is ignored; hashes are always reported as
+// DHash. Returns a *FormatError if the image cannot be decoded or is larger
+// than 2^28 pixels.
+func NewPerceptualHasher(_ ...Option) Hasher {
+	return &perceptualHasher{}
+}
+
+func (h *perceptualHasher) Hash(r io.Reader) ([]byte, error) {
+	reads := &readErrorRecorder{r: r}
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(reads, &header))
+	if err != nil {
+		return nil, formatError("image", fmt.Errorf("failed to decode image: %w", err), reads)
+	}
+	if pixels := int64(config.Width) * int64(config.Height); pixels > perceptualMaxPixels {
+		return nil, formatError("image", fmt.Errorf("image of %dx%d pixels is too large", config.Width, config.Height), reads)
+	}
+	img, _, err := image.Decode(io.MultiReader(&header, reads))
+	if err != nil {
+		return nil, formatError("image", fmt.Errorf("failed to decode image: %w", err), reads)
+	}
+	return binary.BigEndian.AppendUint64(nil, dHash(img)), nil
+}
+
+func (h *perceptualHasher) Similarity(a, b []byte) (float64, error) {
+	if len(a) != 8 || len(b) != 8 {
+		return 0, errors.New("not a difference hash")
+	}
+	distance := bits.OnesCount64(binary.BigEndian.Uint64(a) ^ binary.BigEndian.Uint64(b))
+	return 1 - float64(distance)/64, nil
+}
+
+func (h *perceptualHasher) Algorithm() Algorithm {
+	return DHash
+}
+
+func (h *perceptualHasher) Name() string {
+	return "perceptual"
+}
+
+// dHash computes the difference hash of img.
+func dHash(img image.Image) uint64 {
+	grid := averageLuminance(img, dHashWidth+1, dHashHeight)
+
+	var hash uint64
+	for y := 0; y < dHashHeight; y++ {
+		row := grid[y*(dHashWidth+1) : (y+1)*(dHashWidth+1)]
+		for x := 0; x < dHashWidth; x++ {
+			hash <<= 1
+			if row[x] < row[x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// averageLuminance divides img into width x height cells and returns the
+// average luminance of each cell in row-major order.
+func averageLuminance(img image.Image, width, height int) []uint32 {
+	bounds := img.Bounds()
+	luminance := luminanceFunc(img)
+	sums := make([]uint64, width*height)
+	counts := make([]uint64, width*height)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		cellY := (y - bounds.Min.Y) * height / bounds.Dy()
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			cell := cellY*width + (x-bounds.Min.X)*width/bounds.Dx()
+			sums[cell] += uint64(luminance(x, y))
+			counts[cell]++
+		}
+	}
+
+	averages := make([]uint32, width*height)
+	for cell := range averages {
+		if counts[cell] > 0 {
+			averages[cell] = uint32(sums[cell] / counts[cell])
+		}
+	}
+	return averages
+}
+
+// luminanceFunc returns a function reporting the 8-bit luminance of the
+// pixels of img, reading the luma plane directly where the format has one.
+func luminanceFunc(img image.Image) func(x, y int) uint32 {
+	switch img := img.(type) {
+	case *image.YCbCr:
+		return func(x, y int) uint32 { return uint32(img.Y[img.YOffset(x, y)]) }
+	case *image.Gray:
+		return func(x, y int) uint32 { return uint32(img.Pix[img.PixOffset(x, y)]) }
+	default:
+		return func(x, y int) uint32 {
+			// Same weights as color.GrayModel, applied to 16-bit channels.
+			r, g, b, _ := img.At(x, y).RGBA()
+			return (19595*r + 38470*g + 7471*b + 1<<15) >> 24
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package hasher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// gradientPng returns a PNG image of the given size whose brightness
+// increases from left to right.
+func gradientPng(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8(x * 255 / width)})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// gifHeader returns the header of a GIF image declaring the given size.
+func gifHeader(width, height uint16) []byte {
+	header := []byte("GIF89a")
+	header = binary.LittleEndian.AppendUint16(header, width)
+	header = binary.LittleEndian.AppendUint16(header, height)
+	return append(header, 0, 0, 0)
+}
+
+func TestPerceptualHasherMatchesResizedImage(t *testing.T) {
+	h := NewPerceptualHasher().(SimilarityHasher)
+	small, err := h.Hash(bytes.NewReader(gradientPng(t, 36, 32)))
+	if err != nil {
+		t.Fatalf("Hash() of small image failed: %v", err)
+	}
+	large, err := h.Hash(bytes.NewReader(gradientPng(t, 72, 64)))
+	if err != nil {
+		t.Fatalf("Hash() of large image failed: %v", err)
+	}
+	if similarity, err := h.Similarity(small, large); err != nil || similarity != 1 {
+		t.Errorf("Similarity() = %v, %v, want 1", similarity, err)
+	}
+}
+
+func TestPerceptualHasherRejectsInvalidImages(t *testing.T) {
+	tests := []struct {
+		name  string
+		image []byte
+	}{
+		{"empty", nil},
+		{"unsupported format", []byte("BM")},
+		{"too many pixels", gifHeader(65535, 65535)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewPerceptualHasher().Hash(bytes.NewReader(test.image))
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Errorf("Hash() error = %v, want a *FormatError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package hasher
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Registration describes a Hasher implementation available by name.
+type Registration struct {
+	// Name is the unique name the hasher is registered under.
+	Name string
+	// Description is a short, human-readable summary of the hasher.
+	Description string
+	// New creates the hasher with the given options.
+	New func(options ...Option) Hasher
+}
+
+var (
+	registry     = make(map[string]Registration)
+	registryLock sync.RWMutex
+)
+
+// Register makes a hasher available by name.
+//
+// Register is intended to be called from init functions. It panics if the
+// name is empty, already registered, or the registration has no constructor.
+func Register(registration Registration) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if registration.Name == "" || registration.New == nil {
+		panic("hasher: Register called with incomplete registration")
+	}
+	if _, exists := registry[registration.Name]; exists {
+		panic(fmt.Sprintf("hasher: Register called twice for %q", registration.Name))
+	}
+	registry[registration.Name] = registration
+}
+
+// Lookup returns the hasher registered under name.
+// The boolean result reports whether such a hasher exists.
+func Lookup(name string) (Registration, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	registration, ok := registry[name]
+	return registration, ok
+}
+
+// Registrations returns all registered hashers sorted by name.
+func Registrations() []Registration {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	registrations := make([]Registration, 0, len(registry))
+	for _, registration := range registry {
+		registrations = append(registrations, registration)
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].Name < registrations[j].Name
+	})
+	return registrations
+}
@@ -0,0 +1,16 @@
+package hasher
+
+// SimilarityHasher is a Hasher whose hashes are similarity signatures.
+//
+// Unlike cryptographic digests, signatures of similar content are similar
+// themselves, so comparing two signatures estimates how alike the content
+// they were computed from is. Identical content still yields identical
+// signatures.
+type SimilarityHasher interface {
+	Hasher
+
+	// Similarity estimates the similarity of the content two hashes were
+	// computed from, ranging from 0 (unrelated) to 1 (indistinguishable).
+	// Returns an error if either hash was not produced by this hasher.
+	Similarity(a, b []byte) (float64, error)
+}
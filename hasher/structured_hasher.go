@@ -0,0 +1,285 @@
+package hasher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// structuredMaxSize is the size above which documents are hashed raw
+	// instead of being parsed into memory.
+	structuredMaxSize = 64 << 20
+
+	// structuredCanonical prefixes the hashed canonical form of a document.
+	structuredCanonical = 'C'
+	// structuredRaw prefixes the hashed raw content of a document that could
+	// not be parsed, so it never collides with a canonical form.
+	structuredRaw = 'R'
+)
+
+func init() {
+	Register(Registration{
+		Name:        "structured",
+		Description: "Hash JSON and YAML documents by content, ignoring key order and formatting",
+		New:         NewStructuredHasher,
+	})
+}
+
+// structuredHasher computes hashes of the data in JSON and YAML documents.
+type structuredHasher struct {
+	algorithm Algorithm
+}
+
+// NewStructuredHasher returns a Hasher that hashes the data of JSON and YAML
+// documents with the algorithm selected by options, SHA-256 by default.
+//
+// The document is parsed and hashed in a canonical form in which object
+// keys are sorted and numbers are normalized, so 1, 1.0 and 1e0 are equal.
+// Formatting, key order, comments and the choice between JSON and YAML make
+// no difference. YAML is only accepted if its documents are mappings or
+// sequences, so plain text is not mistaken for a YAML string. A YAML stream
+// of several documents is not the same as a sequence of those documents.
+//
+// Content that parses as neither, or is larger than 64 MiB, is hashed raw
+// instead of failing. Raw hashes never match canonical ones.
+func NewStructuredHasher(options ...Option) Hasher {
+	c := newConfig(options)
+	return &structuredHasher{algorithm: c.algorithm}
+}
+
+func (h *structuredHasher) Hash(r io.Reader) ([]byte, error) {
+	hash := h.algorithm.New()
+
+	content, err := io.ReadAll(io.LimitReader(r, structuredMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) <= structuredMaxSize {
+		if canonical, ok := canonicalizeStructured(content); ok {
+			_, _ = hash.Write([]byte{structuredCanonical})
+			_, _ = hash.Write(canonical)
+			return hash.Sum(nil), nil
+		}
+	}
+
+	_, _ = hash.Write([]byte{structuredRaw})
+	_, _ = hash.Write(content)
+	if _, err := io.Copy(hash, r); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+func (h *structuredHasher) Algorithm() Algorithm {
+	return h.algorithm
+}
+
+func (h *structuredHasher) Name() string {
+	return "structured"
+}
+
+// canonicalizeStructured returns the canonical form of a JSON or YAML
+// document. The boolean result reports whether content could be parsed.
+func canonicalizeStructured(content []byte) ([]byte, bool) {
+	if value, err := parseJSON(content); err == nil {
+		return appendCanonical(nil, value)
+	}
+	if values, err := parseYAML(content); err == nil {
+		return appendCanonical(nil, values)
+	}
+	return nil, false
+}
+
+// parseJSON parses a single JSON value, keeping numbers as json.Number.
+func parseJSON(content []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("trailing data after JSON value")
+	}
+	return value, nil
+}
+
+// yamlStream holds the documents of a YAML stream of several documents. It
+// has its own canonical encoding, so the stream never matches a sequence of
+// the same documents, such as a JSON array.
+type yamlStream []interface{}
+
+// parseYAML parses a stream of YAML documents, each of which must be a
+// mapping or sequence. A stream of a single document yields that document,
+// and a stream of several a yamlStream.
+func parseYAML(content []byte) (interface{}, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+
+	var documents []interface{}
+	for {
+		var document interface{}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch document.(type) {
+		case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+		default:
+			return nil, errors.New("YAML document is not a mapping or sequence")
+		}
+		documents = append(documents, document)
+	}
+
+	switch len(documents) {
+	case 0:
+		return nil, errors.New("empty YAML stream")
+	case 1:
+		return documents[0], nil
+	default:
+		return yamlStream(documents), nil
+	}
+}
+
+// appendCanonical appends the canonical encoding of value to b.
+//
+// Every value is encoded as a type tag followed by its length-prefixed
+// content, so distinct values never share an encoding. The boolean result
+// is false if value holds a type that has no canonical encoding.
+func appendCanonical(b []byte, value interface{}) ([]byte, bool) {
+	switch value := value.(type) {
+	case nil:
+		return append(b, 'n'), true
+	case bool:
+		if value {
+			return append(b, 't'), true
+		}
+		return append(b, 'f'), true
+	case string:
+		return appendTagged(b, 's', value), true
+	case time.Time:
+		return appendTagged(b, 's', value.Format(time.RFC3339Nano)), true
+	case json.Number:
+		return appendTagged(b, 'd', canonicalNumber(string(value))), true
+	case int:
+		return appendTagged(b, 'd', canonicalNumber(strconv.Itoa(value))), true
+	case int64:
+		return appendTagged(b, 'd', canonicalNumber(strconv.FormatInt(value, 10))), true
+	case uint64:
+		return appendTagged(b, 'd', canonicalNumber(strconv.FormatUint(value, 10))), true
+	case float64:
+		return appendTagged(b, 'd', canonicalNumber(strconv.FormatFloat(value, 'g', -1, 64))), true
+	case []interface{}:
+		return appendCanonicalList(b, 'a', value)
+	case yamlStream:
+		return appendCanonicalList(b, 'm', value)
+	case map[string]interface{}:
+		entries := make(map[interface{}]interface{}, len(value))
+		for key, element := range value {
+			entries[key] = element
+		}
+		return appendCanonicalMap(b, entries)
+	case map[interface{}]interface{}:
+		return appendCanonicalMap(b, value)
+	default:
+		return nil, false
+	}
+}
+
+// appendCanonicalList appends the tag, length and canonical encodings of
+// the elements of a list to b.
+func appendCanonicalList(b []byte, tag byte, elements []interface{}) ([]byte, bool) {
+	b = binary.AppendUvarint(append(b, tag), uint64(len(elements)))
+	for _, element := range elements {
+		var ok bool
+		if b, ok = appendCanonical(b, element); !ok {
+			return nil, false
+		}
+	}
+	return b, true
+}
+
+// appendCanonicalMap appends the canonical encoding of a mapping to b,
+// with entries sorted by the canonical encoding of their keys.
+func appendCanonicalMap(b []byte, value map[interface{}]interface{}) ([]byte, bool) {
+	type entry struct{ key, value []byte }
+	entries := make([]entry, 0, len(value))
+	for key, element := range value {
+		encodedKey, ok := appendCanonical(nil, key)
+		if !ok {
+			return nil, false
+		}
+		encodedValue, ok := appendCanonical(nil, element)
+		if !ok {
+			return nil, false
+		}
+		entries = append(entries, entry{encodedKey, encodedValue})
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+
+	b = binary.AppendUvarint(append(b, 'o'), uint64(len(entries)))
+	for _, e := range entries {
+		b = append(append(b, e.key...), e.value...)
+	}
+	return b, true
+}
+
+// appendTagged appends the tag and length-prefixed s to b.
+func appendTagged(b []byte, tag byte, s string) []byte {
+	b = binary.AppendUvarint(append(b, tag), uint64(len(s)))
+	return append(b, s...)
+}
+
+// canonicalNumber normalizes a decimal number literal to the form
+// [-]DIGITSeEXPONENT, where DIGITS has no leading or trailing zeros.
+// Literals that are not decimal numbers, such as NaN, are returned as is.
+func canonicalNumber(literal string) string {
+	if f, err := strconv.ParseFloat(literal, 64); err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return literal
+	}
+
+	sign, unsigned := "", literal
+	if strings.HasPrefix(unsigned, "-") || strings.HasPrefix(unsigned, "+") {
+		sign, unsigned = unsigned[:1], unsigned[1:]
+	}
+	mantissa, exponentLiteral, hasExponent := strings.Cut(strings.ToLower(unsigned), "e")
+	exponent := 0
+	if hasExponent {
+		var err error
+		if exponent, err = strconv.Atoi(exponentLiteral); err != nil {
+			return literal
+		}
+	}
+	integer, fraction, _ := strings.Cut(mantissa, ".")
+	digits := integer + fraction
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return literal
+	}
+	exponent -= len(fraction)
+
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return "0"
+	}
+	trimmed := strings.TrimRight(digits, "0")
+	exponent += len(digits) - len(trimmed)
+
+	if sign == "+" {
+		sign = ""
+	}
+	return fmt.Sprintf("%s%se%d", sign, trimmed, exponent)
+}
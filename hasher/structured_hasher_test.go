@@ -0,0 +1,109 @@
+package hasher
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalNumber(t *testing.T) {
+	tests := []struct {
+		literal   string
+		canonical string
+	}{
+		{"1", "1e0"},
+		{"1.0", "1e0"},
+		{"1e0", "1e0"},
+		{"1E0", "1e0"},
+		{"+1", "1e0"},
+		{"10", "1e1"},
+		{"1000", "1e3"},
+		{"1e3", "1e3"},
+		{"007", "7e0"},
+		{"0.5", "5e-1"},
+		{".5", "5e-1"},
+		{"5e-1", "5e-1"},
+		{"-1.50", "-15e-1"},
+		{"0", "0"},
+		{"-0", "0"},
+		{"0.000", "0"},
+		{"123456789012345678901234567890", "12345678901234567890123456789e1"},
+		{"NaN", "NaN"},
+		{"-Inf", "-Inf"},
+		{"1e", "1e"},
+		{"0x10", "0x10"},
+		{"abc", "abc"},
+	}
+	for _, test := range tests {
+		if canonical := canonicalNumber(test.literal); canonical != test.canonical {
+			t.Errorf("canonicalNumber(%q) = %q, want %q", test.literal, canonical, test.canonical)
+		}
+	}
+}
+
+func TestCanonicalizeStructured(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		ok      bool
+	}{
+		{"JSON object", `{"a": 1}`, true},
+		{"JSON array", `[1, 2]`, true},
+		{"JSON scalar", `"text"`, true},
+		{"YAML mapping", "a: 1\nb: [2, 3]\n", true},
+		{"YAML stream", "a: 1\n---\nb: 2\n", true},
+		{"YAML scalar", "just some text\n", false},
+		{"YAML stream with scalar", "a: 1\n---\ntext\n", false},
+		{"empty", "", false},
+		{"invalid", "{a: [", false},
+		{"JSON with trailing data", `{"a": 1} {"b": 2}`, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, ok := canonicalizeStructured([]byte(test.content)); ok != test.ok {
+				t.Errorf("canonicalizeStructured(%q) ok = %v, want %v", test.content, ok, test.ok)
+			}
+		})
+	}
+}
+
+func TestStructuredHasherEquality(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{"key order", `{"a": 1, "b": 2}`, `{"b": 2, "a": 1}`, true},
+		{"formatting", `{"a":[1,2]}`, "{\n  \"a\": [\n    1,\n    2\n  ]\n}\n", true},
+		{"JSON and YAML", `{"a": {"b": [true, null]}}`, "a:\n  b:\n    - true\n    - null\n", true},
+		{"YAML comments", "a: 1 # one\n", "# header\na: 1\n", true},
+		{"number forms", `[1, 10, 0.5]`, `[1.0, 1e1, 5e-1]`, true},
+		{"YAML and JSON numbers", `{"n": 1.0}`, "n: 1\n", true},
+		{"identical YAML streams", "a: 1\n---\nb: 2\n", "a: 1\n---\nb: 2\n", true},
+		{"identical plain text", "not structured", "not structured", true},
+		{"array order", `[1, 2]`, `[2, 1]`, false},
+		{"string and number", `{"a": "1"}`, `{"a": 1}`, false},
+		{"null and missing key", `{"a": 1, "b": null}`, `{"a": 1}`, false},
+		{"empty array and object", `[]`, `{}`, false},
+		{"nested and flat arrays", `[[1, 2]]`, `[1, 2]`, false},
+		{"YAML stream and JSON array", "a: 1\n---\nb: 2\n", `[{"a": 1}, {"b": 2}]`, false},
+		{"YAML stream order", "a: 1\n---\nb: 2\n", "b: 2\n---\na: 1\n", false},
+		{"JSON string and plain text", `"text"`, "text", false},
+	}
+	h := NewStructuredHasher()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := h.Hash(strings.NewReader(test.a))
+			if err != nil {
+				t.Fatalf("Hash(%q) failed: %v", test.a, err)
+			}
+			b, err := h.Hash(strings.NewReader(test.b))
+			if err != nil {
+				t.Fatalf("Hash(%q) failed: %v", test.b, err)
+			}
+			if bytes.Equal(a, b) != test.same {
+				t.Errorf("Hash(%q) = %x and Hash(%q) = %x, want same = %v", test.a, a, test.b, b, test.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package hasher
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TextNormalization is a set of differences the text hasher ignores.
+type TextNormalization uint
+
+const (
+	// NormalizeLineEndings treats CRLF and lone CR line endings as LF.
+	NormalizeLineEndings TextNormalization = 1 << iota
+	// NormalizeTrailingWhitespace ignores spaces and tabs at the end of lines.
+	NormalizeTrailingWhitespace
+	// NormalizeBOM ignores a UTF-8 byte order mark at the start of the text.
+	NormalizeBOM
+	// NormalizeFinalNewline ignores line endings at the end of the text, so
+	// a missing or extra final newline makes no difference.
+	NormalizeFinalNewline
+
+	// AllTextNormalizations is the set of all normalizations, the default.
+	AllTextNormalizations = NormalizeLineEndings | NormalizeTrailingWhitespace | NormalizeBOM | NormalizeFinalNewline
+)
+
+// textNormalizationNames maps each normalization to its name.
+var textNormalizationNames = []struct {
+	normalization TextNormalization
+	name          string
+}{
+	{NormalizeLineEndings, "line-endings"},
+	{NormalizeTrailingWhitespace, "trailing-whitespace"},
+	{NormalizeBOM, "bom"},
+	{NormalizeFinalNewline, "final-newline"},
+}
+
+var (
+	// utf8BOM is the UTF-8 encoded byte order mark.
+	utf8BOM = []byte{0xef, 0xbb, 0xbf}
+	// utf16BOMs are the byte order marks of UTF-16 text.
+	utf16BOMs = [][]byte{{0xfe, 0xff}, {0xff, 0xfe}}
+)
+
+func init() {
+	Register(Registration{
+		Name:        "text",
+		Description: "Hash text ignoring line endings, trailing whitespace and byte order marks",
+		New:         NewTextHasher,
+	})
+}
+
+// TextNormalizationNames returns the names of all text normalizations.
+func TextNormalizationNames() []string {
+	names := make([]string, 0, len(textNormalizationNames))
+	for _, entry := range textNormalizationNames {
+		names = append(names, entry.name)
+	}
+	return names
+}
+
+// ParseTextNormalization returns the set of normalizations named by names.
+// Returns an error if a name is unknown.
+func ParseTextNormalization(names []string) (TextNormalization, error) {
+	var normalization TextNormalization
+	for _, name := range names {
+		found := false
+		for _, entry := range textNormalizationNames {
+			if strings.TrimSpace(name) == entry.name {
+				normalization |= entry.normalization
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown text normalization %q", name)
+		}
+	}
+	return normalization, nil
+}
+
+// textHasher computes hashes of normalized text.
+type textHasher struct {
+	algorithm     Algorithm
+	normalization TextNormalization
+}
+
+// NewTextHasher returns a Hasher that hashes text with the algorithm
+// selected by options, SHA-256 by default, after normalizing it.
+//
+// The normalizations applied are set with WithTextNormalization and default
+// to AllTextNormalizations, so files differing only in line endings,
+// trailing whitespace, a byte order mark or the final newline hash the
+// same. Text is normalized while streaming; only whitespace and line
+// endings whose fate is not yet known are buffered. Normalization assumes
+// an ASCII-compatible encoding such as UTF-8, so UTF-16 text is hashed as
+// is.
+func NewTextHasher(options ...Option) Hasher {
+	c := newConfig(options)
+	return &textHasher{algorithm: c.algorithm, normalization: c.textNormalization}
+}
+
+func (h *textHasher) Hash(r io.Reader) ([]byte, error) {
+	reader := bufio.NewReader(r)
+	hash := h.algorithm.New()
+	head, _ := reader.Peek(len(utf8BOM))
+
+	for _, bom := range utf16BOMs {
+		if bytes.HasPrefix(head, bom) {
+			if _, err := io.Copy(hash, reader); err != nil {
+				return nil, err
+			}
+			return hash.Sum(nil), nil
+		}
+	}
+	if h.normalization&NormalizeBOM != 0 && bytes.Equal(head, utf8BOM) {
+		_, _ = reader.Discard(len(utf8BOM))
+	}
+
+	normalizer := &textNormalizer{w: hash, normalization: h.normalization}
+	if _, err := io.Copy(normalizer, reader); err != nil {
+		return nil, err
+	}
+	if err := normalizer.Close(); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+func (h *textHasher) Algorithm() Algorithm {
+	return h.algorithm
+}
+
+func (h *textHasher) Name() string {
+	return "text"
+}
+
+// textNormalizer is a writer normalizing line endings and trailing
+// whitespace of the text written to it before passing it on to w.
+type textNormalizer struct {
+	w             io.Writer
+	normalization TextNormalization
+	// out collects the normalized output of a single Write.
+	out []byte
+	// pendingCR reports whether the last byte was a CR, which may start a
+	// CRLF line ending.
+	pendingCR bool
+	// whitespace holds spaces and tabs that are dropped if the line ends
+	// before more content follows.
+	whitespace []byte
+	// lineEndings holds line endings that are dropped if the text ends
+	// before more content follows.
+	lineEndings []byte
+}
+
+func (n *textNormalizer) Write(p []byte) (int, error) {
+	n.out = n.out[:0]
+	for _, c := range p {
+		n.process(c)
+	}
+	if _, err := n.w.Write(n.out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close flushes the normalized end of the text, dropping trailing
+// whitespace and line endings as configured.
+func (n *textNormalizer) Close() error {
+	n.out = n.out[:0]
+	n.resolveCR()
+	_, err := n.w.Write(n.out)
+	return err
+}
+
+// process normalizes the byte c.
+func (n *textNormalizer) process(c byte) {
+	if n.pendingCR {
+		if c == '\n' {
+			n.pendingCR = false
+			n.endLine("\r\n")
+			return
+		}
+		n.resolveCR()
+	}
+
+	switch {
+	case c == '\r':
+		n.pendingCR = true
+	case c == '\n':
+		n.endLine("\n")
+	case (c == ' ' || c == '\t') && n.normalization&NormalizeTrailingWhitespace != 0:
+		n.whitespace = append(n.whitespace, c)
+	default:
+		n.content(c)
+	}
+}
+
+// resolveCR handles a pending CR not followed by LF, which ends a line
+// only when normalizing line endings.
+func (n *textNormalizer) resolveCR() {
+	if !n.pendingCR {
+		return
+	}
+	n.pendingCR = false
+	if n.normalization&NormalizeLineEndings != 0 {
+		n.endLine("\r")
+	} else {
+		n.content('\r')
+	}
+}
+
+// content emits the content byte c along with the whitespace and line
+// endings held back before it.
+func (n *textNormalizer) content(c byte) {
+	n.out = append(n.out, n.lineEndings...)
+	n.out = append(n.out, n.whitespace...)
+	n.out = append(n.out, c)
+	n.lineEndings = n.lineEndings[:0]
+	n.whitespace = n.whitespace[:0]
+}
+
+// endLine ends the current line with lineEnding, dropping its trailing
+// whitespace.
+func (n *textNormalizer) endLine(lineEnding string) {
+	if n.normalization&NormalizeLineEndings != 0 {
+		lineEnding = "\n"
+	}
+	n.whitespace = n.whitespace[:0]
+	if n.normalization&NormalizeFinalNewline != 0 {
+		n.lineEndings = append(n.lineEndings, lineEnding...)
+	} else {
+		n.out = append(n.out, lineEnding...)
+	}
+}
@@ -0,0 +1,115 @@
+package hasher
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// normalizeText returns text normalized by a textNormalizer, written to it
+// in pieces of at most size bytes.
+func normalizeText(t *testing.T, text string, normalization TextNormalization, size int) string {
+	t.Helper()
+	var out bytes.Buffer
+	normalizer := &textNormalizer{w: &out, normalization: normalization}
+	for len(text) > 0 {
+		n := min(size, len(text))
+		if _, err := normalizer.Write([]byte(text[:n])); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+		text = text[n:]
+	}
+	if err := normalizer.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	return out.String()
+}
+
+func TestTextNormalizer(t *testing.T) {
+	tests := []struct {
+		name          string
+		normalization TextNormalization
+		text          string
+		normalized    string
+	}{
+		{"all: CRLF and trailing whitespace", AllTextNormalizations, "a \r\nb\t\r\n\r\n", "a\nb"},
+		{"all: lone CR", AllTextNormalizations, "a\rb\r", "a\nb"},
+		{"all: inner whitespace kept", AllTextNormalizations, "a  b\tc", "a  b\tc"},
+		{"all: leading newlines kept", AllTextNormalizations, "\n\na", "\n\na"},
+		{"all: blank lines kept", AllTextNormalizations, "a \n \nb", "a\n\nb"},
+		{"all: whitespace-only text", AllTextNormalizations, " \t\r\n ", ""},
+		{"none", 0, "a \r\nb\r", "a \r\nb\r"},
+		{"line endings", NormalizeLineEndings, "a \r\nb\rc\n", "a \nb\nc\n"},
+		{"trailing whitespace", NormalizeTrailingWhitespace, "a \r\nb  ", "a\r\nb"},
+		{"trailing whitespace before lone CR", NormalizeTrailingWhitespace, "a \rb", "a \rb"},
+		{"final newline", NormalizeFinalNewline, "a\r\nb\r\n\n", "a\r\nb"},
+		{"final newline without line endings", NormalizeFinalNewline, "a\r", "a\r"},
+		{"line endings and final newline", NormalizeLineEndings | NormalizeFinalNewline, "a \r\n\r", "a "},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, size := range []int{1, 2, len(test.text) + 1} {
+				if normalized := normalizeText(t, test.text, test.normalization, size); normalized != test.normalized {
+					t.Errorf("normalized %q in %d-byte writes to %q, want %q", test.text, size, normalized, test.normalized)
+				}
+			}
+		})
+	}
+}
+
+func TestTextHasherEquality(t *testing.T) {
+	tests := []struct {
+		name          string
+		normalization TextNormalization
+		a, b          string
+		same          bool
+	}{
+		{"line endings", AllTextNormalizations, "a\r\nb\r\n", "a\nb", true},
+		{"UTF-8 BOM", AllTextNormalizations, "\xef\xbb\xbfa\n", "a\n", true},
+		{"UTF-8 BOM kept", NormalizeLineEndings, "\xef\xbb\xbfa\n", "a\n", false},
+		{"BOM in the middle", AllTextNormalizations, "a\xef\xbb\xbf", "a", false},
+		{"UTF-16 hashed as is", AllTextNormalizations, "\xff\xfea\x00\r\x00\n\x00", "\xff\xfea\x00\n\x00", false},
+		{"UTF-16 identical", AllTextNormalizations, "\xff\xfea\x00", "\xff\xfea\x00", true},
+		{"different content", AllTextNormalizations, "a\n", "b\n", false},
+		{"final newline kept", NormalizeLineEndings, "a", "a\n", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewTextHasher(WithTextNormalization(test.normalization))
+			a, err := h.Hash(strings.NewReader(test.a))
+			if err != nil {
+				t.Fatalf("Hash(%q) failed: %v", test.a, err)
+			}
+			b, err := h.Hash(strings.NewReader(test.b))
+			if err != nil {
+				t.Fatalf("Hash(%q) failed: %v", test.b, err)
+			}
+			if bytes.Equal(a, b) != test.same {
+				t.Errorf("Hash(%q) = %x and Hash(%q) = %x, want same = %v", test.a, a, test.b, b, test.same)
+			}
+		})
+	}
+}
+
+func TestParseTextNormalization(t *testing.T) {
+	tests := []struct {
+		name          string
+		names         []string
+		normalization TextNormalization
+		ok            bool
+	}{
+		{"none", nil, 0, true},
+		{"one", []string{"bom"}, NormalizeBOM, true},
+		{"several", []string{"line-endings", " final-newline "}, NormalizeLineEndings | NormalizeFinalNewline, true},
+		{"all", TextNormalizationNames(), AllTextNormalizations, true},
+		{"unknown", []string{"bom", "tabs"}, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			normalization, err := ParseTextNormalization(test.names)
+			if normalization != test.normalization || (err == nil) != test.ok {
+				t.Errorf("ParseTextNormalization(%q) = %v, %v, want %v, ok = %v", test.names, normalization, err, test.normalization, test.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package hasher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// wavFormatPCM is the WAVE format tag for integer PCM.
+	wavFormatPCM = 0x0001
+	// wavFormatExtensible is the WAVE format tag deferring to a sub-format.
+	wavFormatExtensible = 0xfffe
+)
+
+func init() {
+	RegisterAudioDecoder(wavDecoder{})
+}
+
+// wavDecoder decodes integer PCM RIFF WAVE files for the audio hasher.
+type wavDecoder struct{}
+
+func (wavDecoder) Detect(head []byte) bool {
+	return len(head) >= 12 && bytes.Equal(head[0:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WAVE"))
+}
+
+func (wavDecoder) Decode(r io.Reader, w PCMWriter) error {
+	if _, err := io.CopyN(io.Discard, r, 12); err != nil {
+		return err
+	}
+
+	var layout *sampleLayout
+	var channels int
+	for {
+		header, err := readChunkHeader(r, binary.LittleEndian)
+		if err == io.EOF {
+			return errors.New("WAVE file has no data chunk")
+		}
+		if err != nil {
+			return err
+		}
+
+		switch header.id {
+		case "fmt ":
+			format, parsed, err := parseWavFormat(r, header.size)
+			if err != nil {
+				return err
+			}
+			if err := w.WriteFormat(format); err != nil {
+				return err
+			}
+			layout, channels = &parsed, int(format.Channels)
+		case "data":
+			if layout == nil {
+				return errors.New("WAVE data chunk precedes fmt chunk")
+			}
+			return decodePCMData(r, int64(header.size), *layout, channels, w)
+		default:
+			if err := skipChunk(r, header.size); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseWavFormat parses the body of a WAVE fmt chunk of the given size.
+func parseWavFormat(r io.Reader, size uint32) (PCMFormat, sampleLayout, error) {
+	if size < 16 {
+		return PCMFormat{}, sampleLayout{}, errors.New("WAVE fmt chunk too short")
+	}
+	body := make([]byte, int(size)+int(size&1))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return PCMFormat{}, sampleLayout{}, err
+	}
+
+	tag := binary.LittleEndian.Uint16(body[0:])
+	channels := binary.LittleEndian.Uint16(body[2:])
+	sampleRate := binary.LittleEndian.Uint32(body[4:])
+	containerBits := int(binary.LittleEndian.Uint16(body[14:]))
+	validBits := containerBits
+
+	if tag == wavFormatExtensible && size >= 40 {
+		if bits := int(binary.LittleEndian.Uint16(body[18:])); bits != 0 {
+			validBits = bits
+		}
+		tag = binary.LittleEndian.Uint16(body[24:])
+	}
+	if tag != wavFormatPCM {
+		return PCMFormat{}, sampleLayout{}, fmt.Errorf("unsupported WAVE format 0x%04x", tag)
+	}
+	if channels == 0 {
+		return PCMFormat{}, sampleLayout{}, errors.New("WAVE file has no channels")
+	}
+
+	// 8-bit WAVE samples are unsigned; wider ones are signed.
+	layout, err := newSampleLayout(containerBits, validBits, false, containerBits <= 8)
+	if err != nil {
+		return PCMFormat{}, sampleLayout{}, err
+	}
+	format := PCMFormat{
+		SampleRate:    sampleRate,
+		Channels:      channels,
+		BitsPerSample: uint16(validBits),
+	}
+	return format, layout, nil
+}
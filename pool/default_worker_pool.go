@@ -65,9 +65,13 @@ func (w *defaultWorkerPool[I, O]) Submit(task Task[I, O]) error {
 	w.taskCount++
 	w.taskCountLock.Unlock()
 
-	w.taskQueue <- task
-
-	return nil
+	select {
+	case w.taskQueue <- task:
+		return nil
+	case <-w.workerContext.Done():
+		// The pool was stopped while waiting for a worker.
+		return errors.New("worker pool stopped before the task was accepted")
+	}
 }
 
 func (w *defaultWorkerPool[I, O]) Start() {
@@ -128,7 +132,13 @@ func (w *defaultWorkerPool[I, O]) worker(id int) {
 		case <-w.workerContext.Done():
 			return
 		case task := <-w.taskQueue:
-			w.outputChannel <- task.TaskFunction(w.taskContext, task.Input)
+			output := task.TaskFunction(w.taskContext, task.Input)
+			select {
+			case w.outputChannel <- output:
+			case <-w.workerContext.Done():
+				// Nobody collects outputs once the pool is stopped.
+				return
+			}
 			w.taskCountLock.Lock()
 			w.taskCount--
 			if w.submittingComplete && w.taskCount <= 0 {